@@ -65,11 +65,20 @@ func defaultAdapter(config *Config) (resp *Response, err error) {
 		return
 	}
 
+	res.Body = newThrottledReader(req.Context(), res.Body, config.bandwidthBuckets)
+	if config.OnDownloadProgress != nil {
+		res.Body = newProgressReader(res.Body, res.ContentLength, config.ProgressInterval, config.OnDownloadProgress)
+	}
 	resp = &Response{
 		Status:           res.StatusCode,
 		Headers:          res.Header,
 		OriginalResponse: res,
 	}
+	// 流式响应由调用者读取数据
+	if config.Stream {
+		resp.Body = res.Body
+		return
+	}
 	// 读取数据
 	defer res.Body.Close()
 	size := 0
@@ -110,7 +110,7 @@ func Patch(url string, data interface{}, query ...url.Values) (resp *Response, e
 }
 
 // Upload upload file by default instance
-func Upload(url string, file *multipartFile, query ...url.Values) (resp *Response, err error) {
+func Upload(url string, file MultipartBody, query ...url.Values) (resp *Response, err error) {
 	return defaultIns.Upload(url, file, query...)
 }
 
@@ -187,6 +187,8 @@ type Stats struct {
 	ServerProcessingUse int    `json:"serverProcessingUse,omitempty"`
 	ContentTransferUse  int    `json:"contentTransferUse,omitempty"`
 	Size                int    `json:"size,omitempty"`
+	Attempts            int    `json:"attempts,omitempty"`
+	Cache               string `json:"cache,omitempty"`
 }
 
 func ceilToMs(d time.Duration) int {
@@ -205,21 +207,28 @@ func GetStats(conf *Config, err error) (stats Stats) {
 	status := -1
 	resp := conf.Response
 	size := -1
+	cache := ""
 	if resp != nil {
 		status = resp.Status
 		size = len(resp.Data)
+		if sb := getStreamBody(resp); sb != nil {
+			size = sb.bytesRead()
+		}
+		cache = resp.CacheStatus
 	}
 	result := ResultSuccess
 	if err != nil {
 		result = ResultFail
 	}
 	stats = Stats{
-		Route:  conf.Route,
-		Method: conf.Method,
-		Result: result,
-		URI:    conf.GetURL(),
-		Status: status,
-		Size:   size,
+		Route:    conf.Route,
+		Method:   conf.Method,
+		Result:   result,
+		URI:      conf.GetURL(),
+		Status:   status,
+		Size:     size,
+		Attempts: conf.Attempts,
+		Cache:    cache,
 	}
 	ht := conf.HTTPTrace
 	if ht != nil {
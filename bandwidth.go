@@ -0,0 +1,127 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// bandwidthChunkSize max size of data read at once by throttled reader
+const bandwidthChunkSize = 32 * 1024
+
+// throttledReader limits the rate of reading by the token buckets
+type throttledReader struct {
+	ctx       context.Context
+	r         io.ReadCloser
+	buckets   []*tokenBucket
+	chunkSize int
+}
+
+// newBandwidthBucket creates a token bucket of bandwidth,
+// the burst is the bytes of one second
+func newBandwidthBucket(limit int64) *tokenBucket {
+	return newTokenBucket(&RateLimit{
+		Rate:  float64(limit),
+		Burst: int(limit),
+	})
+}
+
+// reserveN takes n tokens from bucket, the tokens may be negative after taken,
+// it returns the duration to wait until the tokens are not negative
+func (tb *tokenBucket) reserveN(n float64) time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.refill(time.Now())
+	tb.tokens -= n
+	if tb.tokens >= 0 || tb.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// newThrottledReader wraps the body to limit the rate of reading,
+// the body is returned directly if there is no bucket
+func newThrottledReader(ctx context.Context, r io.ReadCloser, buckets []*tokenBucket) io.ReadCloser {
+	if len(buckets) == 0 || r == nil || r == http.NoBody {
+		return r
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	chunkSize := bandwidthChunkSize
+	for _, tb := range buckets {
+		if int(tb.burst) < chunkSize {
+			chunkSize = int(tb.burst)
+		}
+	}
+	return &throttledReader{
+		ctx:       ctx,
+		r:         r,
+		buckets:   buckets,
+		chunkSize: chunkSize,
+	}
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > tr.chunkSize {
+		p = p[:tr.chunkSize]
+	}
+	n, err := tr.r.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+	// 读取后按数据量等待，所有bucket均需满足
+	var wait time.Duration
+	for _, tb := range tr.buckets {
+		if d := tb.reserveN(float64(n)); d > wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		return n, err
+	}
+	timer := time.NewTimer(wait)
+	select {
+	case <-tr.ctx.Done():
+		timer.Stop()
+		return n, tr.ctx.Err()
+	case <-timer.C:
+	}
+	return n, err
+}
+
+// Close closes the body
+func (tr *throttledReader) Close() error {
+	return tr.r.Close()
+}
+
+// bandwidthBuckets returns the bandwidth buckets of request, the bucket of instance
+// is shared by all requests, and the bucket of request is shared by its retries
+func (ins *Instance) bandwidthBuckets(config *Config) []*tokenBucket {
+	buckets := make([]*tokenBucket, 0, 2)
+	if config.BandwidthLimit > 0 {
+		if config.bandwidth == nil {
+			config.bandwidth = newBandwidthBucket(config.BandwidthLimit)
+		}
+		buckets = append(buckets, config.bandwidth)
+	}
+	if ins.bandwidth != nil {
+		buckets = append(buckets, ins.bandwidth)
+	}
+	return buckets
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketReserveN(t *testing.T) {
+	assert := assert.New(t)
+
+	tb := newBandwidthBucket(1000)
+	assert.Equal(time.Duration(0), tb.reserveN(1000))
+	d := tb.reserveN(500)
+	assert.True(d > 400*time.Millisecond && d <= 500*time.Millisecond)
+	// 所需等待时长累加
+	d = tb.reserveN(500)
+	assert.True(d > 900*time.Millisecond && d <= time.Second)
+}
+
+func TestThrottledReader(t *testing.T) {
+	assert := assert.New(t)
+
+	data := bytes.Repeat([]byte("a"), 3000)
+	r := newThrottledReader(context.Background(), io.NopCloser(bytes.NewReader(data)), []*tokenBucket{
+		newBandwidthBucket(10000),
+		newBandwidthBucket(2000),
+	})
+	start := time.Now()
+	buf, err := io.ReadAll(r)
+	assert.Nil(err)
+	assert.Nil(r.Close())
+	assert.Equal(data, buf)
+	// 首秒的数据不等待，剩余的1000字节按2000/s限制
+	assert.True(time.Since(start) >= 400*time.Millisecond)
+
+	// context取消
+	ctx, cancel := context.WithCancel(context.Background())
+	r = newThrottledReader(ctx, io.NopCloser(bytes.NewReader(data)), []*tokenBucket{
+		newBandwidthBucket(1000),
+	})
+	cancel()
+	_, err = io.ReadAll(r)
+	assert.Equal(context.Canceled, err)
+
+	// 无限制时直接返回
+	body := io.NopCloser(bytes.NewReader(data))
+	assert.Equal(body, newThrottledReader(context.Background(), body, nil))
+}
+
+func TestBandwidthLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	data := bytes.Repeat([]byte("a"), 30*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			buf, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(buf[:10])
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	// 实例的所有请求共享带宽
+	ins := NewInstance(&InstanceConfig{
+		BaseURL:        server.URL,
+		BandwidthLimit: 20 * 1024,
+	})
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := ins.Get("/")
+			assert.Nil(err)
+			assert.Equal(data, resp.Data)
+		}()
+	}
+	wg.Wait()
+	// 60KB数据，首秒20KB，剩余40KB需要2秒
+	assert.True(time.Since(start) >= 1800*time.Millisecond)
+
+	// 请求的限制
+	ins = NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	})
+	start = time.Now()
+	resp, err := ins.Request(&Config{
+		URL:            "/",
+		Method:         http.MethodPost,
+		Body:           data,
+		BandwidthLimit: 20 * 1024,
+	})
+	assert.Nil(err)
+	assert.Equal(data[:10], resp.Data)
+	assert.True(time.Since(start) >= 400*time.Millisecond)
+}
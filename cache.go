@@ -0,0 +1,409 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CacheMiss the response is fetched from server
+	CacheMiss = "miss"
+	// CacheHit the response is served from cache
+	CacheHit = "hit"
+	// CacheRevalidated the cached response is validated by server(304)
+	CacheRevalidated = "revalidated"
+)
+
+const (
+	headerCacheControl    = "Cache-Control"
+	headerExpires         = "Expires"
+	headerDate            = "Date"
+	headerAge             = "Age"
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerVary            = "Vary"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
+)
+
+type (
+	// CacheEntry cached response
+	CacheEntry struct {
+		// Status status of response
+		Status int
+		// Headers headers of response
+		Headers http.Header
+		// Data original data of response(before transform)
+		Data []byte
+		// VaryHeaders request headers which are listed in the vary header of response
+		VaryHeaders http.Header
+		// ResponseTime the time when the response was received
+		ResponseTime time.Time
+	}
+	// Cache cache store of response
+	Cache interface {
+		// Get gets the entry of key
+		Get(key string) (*CacheEntry, bool)
+		// Set sets the entry of key
+		Set(key string, entry *CacheEntry)
+		// Delete deletes the entry of key
+		Delete(key string)
+	}
+
+	lruCache struct {
+		mutex sync.Mutex
+		size  int
+		ll    *list.List
+		items map[string]*list.Element
+	}
+	lruItem struct {
+		key   string
+		entry *CacheEntry
+	}
+
+	cacheControl map[string]string
+)
+
+// cacheableStatuses statuses which are cacheable by default(RFC 9110)
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// NewLRUCache creates an in-memory lru cache
+func NewLRUCache(size int) Cache {
+	if size <= 0 {
+		size = 128
+	}
+	return &lruCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) Get(key string) (*CacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruItem).entry, true
+}
+
+func (c *lruCache) Set(key string, entry *CacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*lruItem).entry = entry
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruItem{
+		key:   key,
+		entry: entry,
+	})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*lruItem).key)
+	}
+}
+
+func (c *lruCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// parseCacheControl parses the cache control header
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values(headerCacheControl) {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			key := item
+			v := ""
+			if index := strings.Index(item, "="); index != -1 {
+				key = item[:index]
+				v = strings.Trim(strings.TrimSpace(item[index+1:]), `"`)
+			}
+			cc[strings.ToLower(strings.TrimSpace(key))] = v
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(key string) bool {
+	_, ok := cc[key]
+	return ok
+}
+
+// seconds returns the seconds value of directive
+func (cc cacheControl) seconds(key string) (time.Duration, bool) {
+	v, ok := cc[key]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.Atoi(v)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return time.Duration(value) * time.Second, true
+}
+
+func getCacheKey(session, method string, req *http.Request) string {
+	key := method + " " + req.URL.String()
+	if session != "" {
+		key = session + " " + key
+	}
+	return key
+}
+
+// varyHeaderNames returns the names of vary header
+func varyHeaderNames(header http.Header) []string {
+	names := make([]string, 0)
+	for _, value := range header.Values(headerVary) {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// isCacheable returns true if the response could be stored
+func isCacheable(req *http.Request, resp *Response) bool {
+	if req.Method != http.MethodGet || !cacheableStatuses[resp.Status] {
+		return false
+	}
+	if parseCacheControl(req.Header).has("no-store") {
+		return false
+	}
+	cc := parseCacheControl(resp.Headers)
+	if cc.has("no-store") {
+		return false
+	}
+	// 带认证信息的请求仅在响应明确允许时缓存(RFC 9111 3.5)，避免响应被其它用户获取
+	if req.Header.Get(headerAuthorization) != "" &&
+		!cc.has("public") &&
+		!cc.has("s-maxage") &&
+		!cc.has("must-revalidate") {
+		return false
+	}
+	for _, name := range varyHeaderNames(resp.Headers) {
+		if name == "*" {
+			return false
+		}
+	}
+	// 有明确的缓存时长或者可用于校验的字段
+	return cc.has("max-age") ||
+		cc.has("no-cache") ||
+		resp.Headers.Get(headerExpires) != "" ||
+		resp.Headers.Get(headerETag) != "" ||
+		resp.Headers.Get(headerLastModified) != ""
+}
+
+// matchVary returns true if the request headers match the vary headers of entry
+func (entry *CacheEntry) matchVary(header http.Header) bool {
+	for _, name := range varyHeaderNames(entry.Headers) {
+		if strings.Join(header.Values(name), ",") != strings.Join(entry.VaryHeaders.Values(name), ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// freshnessLifetime returns the freshness lifetime of entry
+func (entry *CacheEntry) freshnessLifetime() time.Duration {
+	cc := parseCacheControl(entry.Headers)
+	if d, ok := cc.seconds("max-age"); ok {
+		return d
+	}
+	date, err := http.ParseTime(entry.Headers.Get(headerDate))
+	if err != nil {
+		date = entry.ResponseTime
+	}
+	if value := entry.Headers.Get(headerExpires); value != "" {
+		expires, err := http.ParseTime(value)
+		// 非法的expires视为已过期
+		if err != nil || expires.Before(date) {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	// 启发式缓存时长为距离最后修改时间的10%
+	if value := entry.Headers.Get(headerLastModified); value != "" {
+		lastModified, err := http.ParseTime(value)
+		if err == nil && lastModified.Before(date) {
+			return date.Sub(lastModified) / 10
+		}
+	}
+	return 0
+}
+
+// age returns the current age of entry
+func (entry *CacheEntry) age(now time.Time) time.Duration {
+	age := now.Sub(entry.ResponseTime)
+	if age < 0 {
+		age = 0
+	}
+	if value, err := strconv.Atoi(entry.Headers.Get(headerAge)); err == nil && value > 0 {
+		age += time.Duration(value) * time.Second
+	}
+	return age
+}
+
+// isFresh returns true if the entry could be used without validation
+func (entry *CacheEntry) isFresh(reqHeader http.Header, now time.Time) bool {
+	reqCC := parseCacheControl(reqHeader)
+	if reqCC.has("no-cache") || parseCacheControl(entry.Headers).has("no-cache") {
+		return false
+	}
+	age := entry.age(now)
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	return age < entry.freshnessLifetime()
+}
+
+// toResponse converts entry to response
+func (entry *CacheEntry) toResponse(status string) *Response {
+	data := make([]byte, len(entry.Data))
+	copy(data, entry.Data)
+	return &Response{
+		Status:      entry.Status,
+		Headers:     entry.Headers.Clone(),
+		Data:        data,
+		CacheStatus: status,
+	}
+}
+
+func newCacheEntry(req *http.Request, resp *Response, now time.Time) *CacheEntry {
+	data := make([]byte, len(resp.Data))
+	copy(data, resp.Data)
+	varyHeaders := make(http.Header)
+	for _, name := range varyHeaderNames(resp.Headers) {
+		for _, value := range req.Header.Values(name) {
+			varyHeaders.Add(name, value)
+		}
+	}
+	return &CacheEntry{
+		Status:       resp.Status,
+		Headers:      resp.Headers.Clone(),
+		Data:         data,
+		VaryHeaders:  varyHeaders,
+		ResponseTime: now,
+	}
+}
+
+// newCacheAdapter creates an adapter which serves the response from cache,
+// the key of cache is prefixed with session if it's not empty
+func newCacheAdapter(cache Cache, session string, adapter Adapter) Adapter {
+	return func(config *Config) (*Response, error) {
+		req := config.Request
+		key := getCacheKey(session, req.Method, req)
+		// 非安全的请求方法使缓存失效
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			resp, err := adapter(config)
+			if err == nil && resp != nil && resp.Status < 400 {
+				cache.Delete(getCacheKey(session, http.MethodGet, req))
+			}
+			return resp, err
+		}
+		if req.Method != http.MethodGet || config.Stream || parseCacheControl(req.Header).has("no-store") {
+			return adapter(config)
+		}
+
+		entry, ok := cache.Get(key)
+		if ok && !entry.matchVary(req.Header) {
+			entry = nil
+			ok = false
+		}
+		if ok && entry.isFresh(req.Header, time.Now()) {
+			return entry.toResponse(CacheHit), nil
+		}
+		// 添加条件请求的请求头
+		conditional := false
+		if ok {
+			if etag := entry.Headers.Get(headerETag); etag != "" {
+				req.Header.Set(headerIfNoneMatch, etag)
+				conditional = true
+			}
+			if lastModified := entry.Headers.Get(headerLastModified); lastModified != "" {
+				req.Header.Set(headerIfModifiedSince, lastModified)
+				conditional = true
+			}
+		}
+
+		resp, err := adapter(config)
+		if err != nil || resp == nil {
+			return resp, err
+		}
+		now := time.Now()
+		if conditional && resp.Status == http.StatusNotModified {
+			// 使用304的响应头更新缓存
+			headers := entry.Headers.Clone()
+			for name, values := range resp.Headers {
+				if name == headerContentLength {
+					continue
+				}
+				headers[name] = values
+			}
+			entry = &CacheEntry{
+				Status:       entry.Status,
+				Headers:      headers,
+				Data:         entry.Data,
+				VaryHeaders:  entry.VaryHeaders,
+				ResponseTime: now,
+			}
+			cache.Set(key, entry)
+			revalidated := entry.toResponse(CacheRevalidated)
+			revalidated.OriginalResponse = resp.OriginalResponse
+			return revalidated, nil
+		}
+		resp.CacheStatus = CacheMiss
+		if isCacheable(req, resp) {
+			cache.Set(key, newCacheEntry(req, resp, now))
+		} else if ok {
+			cache.Delete(key)
+		}
+		return resp, nil
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	assert := assert.New(t)
+	c := NewLRUCache(2)
+	c.Set("a", &CacheEntry{Status: 1})
+	c.Set("b", &CacheEntry{Status: 2})
+	_, ok := c.Get("a")
+	assert.True(ok)
+	c.Set("c", &CacheEntry{Status: 3})
+	_, ok = c.Get("b")
+	assert.False(ok)
+	entry, ok := c.Get("a")
+	assert.True(ok)
+	assert.Equal(1, entry.Status)
+	c.Delete("a")
+	_, ok = c.Get("a")
+	assert.False(ok)
+}
+
+func TestCacheEntryFreshness(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(10000, 0)
+
+	entry := &CacheEntry{
+		Headers: http.Header{
+			headerCacheControl: []string{"public, max-age=60"},
+		},
+		ResponseTime: now,
+	}
+	assert.Equal(time.Minute, entry.freshnessLifetime())
+	assert.True(entry.isFresh(nil, now.Add(59*time.Second)))
+	assert.False(entry.isFresh(nil, now.Add(61*time.Second)))
+	assert.False(entry.isFresh(http.Header{
+		headerCacheControl: []string{"max-age=10"},
+	}, now.Add(20*time.Second)))
+	assert.False(entry.isFresh(http.Header{
+		headerCacheControl: []string{"no-cache"},
+	}, now))
+
+	entry = &CacheEntry{
+		Headers: http.Header{
+			headerDate:    []string{now.UTC().Format(http.TimeFormat)},
+			headerExpires: []string{now.Add(time.Hour).UTC().Format(http.TimeFormat)},
+			headerAge:     []string{"1800"},
+		},
+		ResponseTime: now,
+	}
+	assert.Equal(time.Hour, entry.freshnessLifetime())
+	assert.Equal(30*time.Minute, entry.age(now))
+
+	entry = &CacheEntry{
+		Headers: http.Header{
+			headerDate:         []string{now.UTC().Format(http.TimeFormat)},
+			headerLastModified: []string{now.Add(-100 * time.Second).UTC().Format(http.TimeFormat)},
+		},
+		ResponseTime: now,
+	}
+	assert.Equal(10*time.Second, entry.freshnessLifetime())
+}
+
+func TestCache(t *testing.T) {
+	t.Run("hit", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		buf := bytes.Buffer{}
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write([]byte("hello world"))
+		_ = w.Close()
+		statuses := make([]string, 0)
+		ins := NewInstance(&InstanceConfig{
+			Cache: NewLRUCache(10),
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				return &Response{
+					Status: 200,
+					Headers: http.Header{
+						headerCacheControl:    []string{"max-age=60"},
+						headerContentEncoding: []string{"gzip"},
+					},
+					Data: buf.Bytes(),
+				}, nil
+			},
+			OnDone: func(config *Config, resp *Response, err error) {
+				statuses = append(statuses, GetStats(config, err).Cache)
+			},
+		})
+		for i := 0; i < 2; i++ {
+			resp, err := ins.Get("/")
+			assert.Nil(err)
+			assert.Equal("hello world", string(resp.Data))
+		}
+		assert.Equal(1, count)
+		assert.Equal([]string{CacheMiss, CacheHit}, statuses)
+
+		// 更新数据后缓存失效
+		_, err := ins.Post("/", "abc")
+		assert.Nil(err)
+		_, err = ins.Get("/")
+		assert.Nil(err)
+		assert.Equal(3, count)
+	})
+
+	t.Run("revalidate", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Cache: NewLRUCache(10),
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				if config.Request.Header.Get(headerIfNoneMatch) == `"abc"` {
+					return &Response{
+						Status: 304,
+					}, nil
+				}
+				headers := make(http.Header)
+				headers.Set(headerETag, `"abc"`)
+				return &Response{
+					Status:  200,
+					Headers: headers,
+					Data:    []byte("hello world"),
+				}, nil
+			},
+		})
+		resp, err := ins.Get("/")
+		assert.Nil(err)
+		assert.Equal(CacheMiss, resp.CacheStatus)
+		resp, err = ins.Get("/")
+		assert.Nil(err)
+		assert.Equal(CacheRevalidated, resp.CacheStatus)
+		assert.Equal(200, resp.Status)
+		assert.Equal("hello world", string(resp.Data))
+		assert.Equal(2, count)
+	})
+
+	t.Run("vary and no-store", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Cache: NewLRUCache(10),
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				cacheControl := "max-age=60"
+				if config.Route == "/no-store" {
+					cacheControl = "no-store"
+				}
+				return &Response{
+					Status: 200,
+					Headers: http.Header{
+						headerCacheControl: []string{cacheControl},
+						headerVary:         []string{"X-Lang"},
+					},
+				}, nil
+			},
+		})
+		newConfig := func(lang string) *Config {
+			return &Config{
+				URL: "/",
+				Headers: http.Header{
+					"X-Lang": []string{lang},
+				},
+			}
+		}
+		resp, _ := ins.Request(newConfig("en"))
+		assert.Equal(CacheMiss, resp.CacheStatus)
+		resp, _ = ins.Request(newConfig("en"))
+		assert.Equal(CacheHit, resp.CacheStatus)
+		resp, _ = ins.Request(newConfig("zh"))
+		assert.Equal(CacheMiss, resp.CacheStatus)
+		assert.Equal(2, count)
+
+		_, _ = ins.Get("/no-store")
+		resp, _ = ins.Get("/no-store")
+		assert.Equal(CacheMiss, resp.CacheStatus)
+		assert.Equal(4, count)
+	})
+
+	t.Run("authorization", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Cache: NewLRUCache(10),
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				cacheControl := "max-age=60"
+				if config.Route == "/public" {
+					cacheControl = "public, max-age=60"
+				}
+				return &Response{
+					Status: 200,
+					Headers: http.Header{
+						headerCacheControl: []string{cacheControl},
+					},
+					Data: []byte(config.Request.Header.Get(headerAuthorization)),
+				}, nil
+			},
+		})
+		newConfig := func(url, authorization string) *Config {
+			return &Config{
+				URL: url,
+				Headers: http.Header{
+					headerAuthorization: []string{authorization},
+				},
+			}
+		}
+		// 带认证信息的响应不缓存
+		resp, _ := ins.Request(newConfig("/", "user1"))
+		assert.Equal(CacheMiss, resp.CacheStatus)
+		resp, _ = ins.Request(newConfig("/", "user2"))
+		assert.Equal(CacheMiss, resp.CacheStatus)
+		assert.Equal("user2", string(resp.Data))
+		assert.Equal(2, count)
+
+		// 响应明确为public则可缓存
+		_, _ = ins.Request(newConfig("/public", "user1"))
+		resp, _ = ins.Request(newConfig("/public", "user2"))
+		assert.Equal(CacheHit, resp.CacheStatus)
+		assert.Equal(3, count)
+	})
+}
@@ -0,0 +1,310 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// CircuitClosed requests are allowed
+	CircuitClosed CircuitState = iota
+	// CircuitOpen requests fail fast
+	CircuitOpen
+	// CircuitHalfOpen limited requests are allowed to probe the downstream
+	CircuitHalfOpen
+)
+
+const (
+	defaultCircuitWindow      = 10 * time.Second
+	defaultCircuitMinRequests = 10
+	defaultCircuitFailureRate = 0.5
+	defaultCircuitCoolDown    = 5 * time.Second
+)
+
+type (
+	// CircuitState state of circuit
+	CircuitState int
+	// CircuitKey returns the key of circuit for the request
+	CircuitKey func(config *Config) string
+	// OnCircuitStateChange on circuit state change event
+	OnCircuitStateChange        func(key string, from, to CircuitState)
+	CircuitStateChangeListeners []OnCircuitStateChange
+
+	// CircuitBreakerConfig config of circuit breaker
+	CircuitBreakerConfig struct {
+		// Key returns the key of circuit, default is CircuitKeyByRoute
+		Key CircuitKey
+		// Window the statistics window of closed state, default is 10s
+		Window time.Duration
+		// MinRequests the min requests of window to calculate failure rate, default is 10
+		MinRequests int
+		// FailureRate the circuit will be opened if the failure rate is gte it, default is 0.5
+		FailureRate float64
+		// CoolDown the duration of open state, default is 5s
+		CoolDown time.Duration
+		// HalfOpenRequests the max probe requests of half open state, default is 1
+		HalfOpenRequests int
+		// IsFailure returns true if the request is failure,
+		// default is returning true if err is not nil or status gte 500
+		IsFailure func(config *Config, resp *Response, err error) bool
+	}
+
+	circuit struct {
+		state       CircuitState
+		windowStart time.Time
+		total       int
+		failures    int
+		openedAt    time.Time
+		probes      int
+		successes   int
+	}
+	circuitBreaker struct {
+		mutex    sync.Mutex
+		conf     *CircuitBreakerConfig
+		circuits map[string]*circuit
+		// emit state change event
+		emit func(key string, from, to CircuitState)
+	}
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitKeyByRoute uses the route of request as circuit key,
+// the host is used if the route is not set, so the requests of
+// the same host share one circuit instead of one circuit per url path
+func CircuitKeyByRoute(config *Config) string {
+	if config.Route != "" {
+		return config.Route
+	}
+	return CircuitKeyByHost(config)
+}
+
+// CircuitKeyByHost uses the host of request as circuit key
+func CircuitKeyByHost(config *Config) string {
+	urlInfo, _ := url.Parse(urlJoin(config.BaseURL, config.URL))
+	if urlInfo != nil {
+		return urlInfo.Host
+	}
+	return ""
+}
+
+func defaultIsFailure(_ *Config, resp *Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp != nil && resp.Status >= 500
+}
+
+func newCircuitBreaker(conf *CircuitBreakerConfig, emit func(key string, from, to CircuitState)) *circuitBreaker {
+	return &circuitBreaker{
+		conf:     conf,
+		circuits: make(map[string]*circuit),
+		emit:     emit,
+	}
+}
+
+func (cb *circuitBreaker) key(config *Config) string {
+	if cb == nil {
+		return ""
+	}
+	if cb.conf.Key != nil {
+		return cb.conf.Key(config)
+	}
+	return CircuitKeyByRoute(config)
+}
+
+func (cb *circuitBreaker) window() time.Duration {
+	if cb.conf.Window > 0 {
+		return cb.conf.Window
+	}
+	return defaultCircuitWindow
+}
+
+func (cb *circuitBreaker) minRequests() int {
+	if cb.conf.MinRequests > 0 {
+		return cb.conf.MinRequests
+	}
+	return defaultCircuitMinRequests
+}
+
+func (cb *circuitBreaker) failureRate() float64 {
+	if cb.conf.FailureRate > 0 {
+		return cb.conf.FailureRate
+	}
+	return defaultCircuitFailureRate
+}
+
+func (cb *circuitBreaker) coolDown() time.Duration {
+	if cb.conf.CoolDown > 0 {
+		return cb.conf.CoolDown
+	}
+	return defaultCircuitCoolDown
+}
+
+func (cb *circuitBreaker) halfOpenRequests() int {
+	if cb.conf.HalfOpenRequests > 0 {
+		return cb.conf.HalfOpenRequests
+	}
+	return 1
+}
+
+func (cb *circuitBreaker) isFailure(config *Config, resp *Response, err error) bool {
+	if cb.conf.IsFailure != nil {
+		return cb.conf.IsFailure(config, resp, err)
+	}
+	return defaultIsFailure(config, resp, err)
+}
+
+// setState sets the state of circuit, it should be called with lock
+func (cb *circuitBreaker) setState(c *circuit, state CircuitState, now time.Time) {
+	c.state = state
+	c.total = 0
+	c.failures = 0
+	c.probes = 0
+	c.successes = 0
+	c.windowStart = now
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+}
+
+// state returns the current state of circuit
+func (cb *circuitBreaker) state(key string) CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	// open状态已超过冷却时间，则视为half open
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= cb.coolDown() {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// allow returns ErrCircuitOpen if the request is not allowed
+func (cb *circuitBreaker) allow(key string) error {
+	if cb == nil {
+		return nil
+	}
+	cb.mutex.Lock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		c = &circuit{
+			windowStart: time.Now(),
+		}
+		cb.circuits[key] = c
+	}
+	from := c.state
+	err := ErrCircuitOpen
+	switch c.state {
+	case CircuitClosed:
+		err = nil
+	case CircuitOpen:
+		now := time.Now()
+		if now.Sub(c.openedAt) >= cb.coolDown() {
+			cb.setState(c, CircuitHalfOpen, now)
+			c.probes++
+			err = nil
+		}
+	case CircuitHalfOpen:
+		if c.probes < cb.halfOpenRequests() {
+			c.probes++
+			err = nil
+		}
+	}
+	to := c.state
+	cb.mutex.Unlock()
+	if from != to {
+		cb.emit(key, from, to)
+	}
+	return err
+}
+
+// report reports the result of request
+func (cb *circuitBreaker) report(key string, failure bool) {
+	cb.mutex.Lock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		cb.mutex.Unlock()
+		return
+	}
+	from := c.state
+	now := time.Now()
+	switch c.state {
+	case CircuitClosed:
+		// 超过统计窗口则重新统计
+		if now.Sub(c.windowStart) >= cb.window() {
+			cb.setState(c, CircuitClosed, now)
+		}
+		c.total++
+		if failure {
+			c.failures++
+		}
+		if c.total >= cb.minRequests() &&
+			float64(c.failures)/float64(c.total) >= cb.failureRate() {
+			cb.setState(c, CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if failure {
+			cb.setState(c, CircuitOpen, now)
+			break
+		}
+		c.successes++
+		if c.successes >= cb.halfOpenRequests() {
+			cb.setState(c, CircuitClosed, now)
+		}
+	}
+	to := c.state
+	cb.mutex.Unlock()
+	if from != to {
+		cb.emit(key, from, to)
+	}
+}
+
+// cancel cancels the request which is allowed but not sent(e.g. rate limited),
+// the probe of half open state is released
+func (cb *circuitBreaker) cancel(key string) {
+	if cb == nil {
+		return
+	}
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	c, ok := cb.circuits[key]
+	if ok && c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// done reports the result of request which is allowed
+func (cb *circuitBreaker) done(key string, config *Config, resp *Response, err error) {
+	if cb == nil {
+		return
+	}
+	cb.report(key, cb.isFailure(config, resp, err))
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("/users/:type", CircuitKeyByRoute(&Config{
+		Route: "/users/:type",
+		URL:   "/users/me",
+	}))
+	// 未设置route则使用host
+	assert.Equal("aslant.site", CircuitKeyByRoute(&Config{
+		BaseURL: "https://aslant.site",
+		URL:     "/users/me?type=1",
+	}))
+	assert.Equal("aslant.site", CircuitKeyByHost(&Config{
+		BaseURL: "https://aslant.site",
+		URL:     "/users/me",
+	}))
+	assert.Equal("npmtrend.com", CircuitKeyByHost(&Config{
+		BaseURL: "https://aslant.site",
+		URL:     "https://npmtrend.com/users/me",
+	}))
+
+	assert.Equal("closed", CircuitClosed.String())
+	assert.Equal("open", CircuitOpen.String())
+	assert.Equal("half-open", CircuitHalfOpen.String())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+
+	customErr := errors.New("custom error")
+	fail := true
+	count := 0
+	changes := make([]string, 0)
+	ins := NewInstance(&InstanceConfig{
+		CircuitBreaker: &CircuitBreakerConfig{
+			MinRequests: 2,
+			FailureRate: 0.5,
+			CoolDown:    10 * time.Millisecond,
+		},
+		Adapter: func(config *Config) (*Response, error) {
+			count++
+			if fail {
+				return nil, customErr
+			}
+			return &Response{
+				Status: 200,
+			}, nil
+		},
+	})
+	ins.Config.AddCircuitStateChangeListener(func(key string, from, to CircuitState) {
+		changes = append(changes, key+":"+from.String()+"->"+to.String())
+	})
+
+	get := func(route string) error {
+		_, err := ins.Request(&Config{
+			URL:   route,
+			Route: route,
+		})
+		return err
+	}
+
+	err := get("/")
+	assert.Equal(customErr, err)
+	assert.Equal(CircuitClosed, ins.GetCircuitState("/"))
+	err = get("/")
+	assert.Equal(customErr, err)
+	assert.Equal(CircuitOpen, ins.GetCircuitState("/"))
+
+	// 熔断后直接失败
+	err = get("/")
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(2, count)
+
+	// 其它route不受影响
+	err = get("/users/me")
+	assert.Equal(customErr, err)
+	assert.Equal(3, count)
+
+	time.Sleep(15 * time.Millisecond)
+	assert.Equal(CircuitHalfOpen, ins.GetCircuitState("/"))
+	// half open探测失败，重新熔断
+	err = get("/")
+	assert.Equal(customErr, err)
+	assert.Equal(CircuitOpen, ins.GetCircuitState("/"))
+
+	time.Sleep(15 * time.Millisecond)
+	fail = false
+	err = get("/")
+	assert.Nil(err)
+	assert.Equal(CircuitClosed, ins.GetCircuitState("/"))
+
+	assert.Equal([]string{
+		"/:closed->open",
+		"/:open->half-open",
+		"/:half-open->open",
+		"/:open->half-open",
+		"/:half-open->closed",
+	}, changes)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	assert := assert.New(t)
+	ins := NewInstance(nil)
+	assert.Equal(CircuitClosed, ins.GetCircuitState("/"))
+}
+
+func TestCircuitBreakerDefaultKey(t *testing.T) {
+	assert := assert.New(t)
+
+	customErr := errors.New("custom error")
+	count := 0
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: "https://aslant.site",
+		CircuitBreaker: &CircuitBreakerConfig{
+			MinRequests: 2,
+			CoolDown:    time.Minute,
+		},
+		RateLimiter: &RateLimiterConfig{
+			Global: &RateLimit{
+				Rate:  0.001,
+				Burst: 3,
+			},
+		},
+		Retry: &RetryPolicy{
+			MaxAttempts: 3,
+			Conditions: []RetryCondition{
+				func(_ *Config, _ *Response, err error) bool {
+					return err == customErr
+				},
+			},
+		},
+		Adapter: func(config *Config) (*Response, error) {
+			count++
+			return nil, customErr
+		},
+	})
+	// 未设置route的请求按host熔断，重试时key不变
+	_, err := ins.Get("/users/1")
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(2, count)
+	assert.Equal(CircuitOpen, ins.GetCircuitState("aslant.site"))
+	_, err = ins.Get("/users/2")
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(2, count)
+
+	// 熔断的请求不消耗令牌
+	tokens, _ := ins.GetRateLimitTokens("")
+	assert.True(tokens >= 1)
+}
+
+func TestCircuitBreakerCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreaker(&CircuitBreakerConfig{
+		MinRequests: 1,
+		CoolDown:    time.Millisecond,
+	}, func(string, CircuitState, CircuitState) {})
+	assert.Nil(cb.allow("key"))
+	cb.report("key", true)
+	assert.Equal(CircuitOpen, cb.state("key"))
+
+	time.Sleep(2 * time.Millisecond)
+	assert.Nil(cb.allow("key"))
+	assert.Equal(ErrCircuitOpen, cb.allow("key"))
+	// 未发送的探测请求释放后可再次探测
+	cb.cancel("key")
+	assert.Nil(cb.allow("key"))
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionGzip gzip encoding of request body
+	CompressionGzip = gzipEncoding
+	// CompressionBr brotli encoding of request body
+	CompressionBr = brEncoding
+	// CompressionZstd zstd encoding of request body
+	CompressionZstd = "zstd"
+
+	compressionChunkSize = 32 * 1024
+)
+
+var ErrCompressionUnsupported = errors.New("compression encoding is not supported")
+
+type (
+	// CompressionConfig compression config of request body
+	CompressionConfig struct {
+		// Encoding the encoding of compression, gzip, br or zstd, default is gzip
+		Encoding string
+		// MinLength the body is compressed only if its length is not less than min length
+		MinLength int
+		// Level the compression level of encoding, the default level is used if it's 0
+		Level int
+	}
+
+	// compressWriter creates a writer which compresses data to w
+	compressWriter func(w io.Writer, level int) (io.WriteCloser, error)
+
+	// compressReader compresses the data of source reader when it's read
+	compressReader struct {
+		src    io.Reader
+		closer io.Closer
+		buf    *bytes.Buffer
+		w      io.WriteCloser
+		chunk  []byte
+		err    error
+	}
+)
+
+var compressWriters = map[string]compressWriter{
+	CompressionGzip: func(w io.Writer, level int) (io.WriteCloser, error) {
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	},
+	CompressionBr: func(w io.Writer, level int) (io.WriteCloser, error) {
+		if level == 0 {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(w, level), nil
+	},
+	CompressionZstd: func(w io.Writer, level int) (io.WriteCloser, error) {
+		opts := make([]zstd.EOption, 0)
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(w, opts...)
+	},
+}
+
+func (c *CompressionConfig) encoding() string {
+	if c.Encoding == "" {
+		return CompressionGzip
+	}
+	return c.Encoding
+}
+
+func (c *CompressionConfig) newWriter(w io.Writer) (io.WriteCloser, error) {
+	fn, ok := compressWriters[c.encoding()]
+	if !ok {
+		return nil, ErrCompressionUnsupported
+	}
+	return fn(w, c.Level)
+}
+
+// compress compresses the data([]byte or io.Reader) of request body and sets
+// the Content-Encoding header, it returns false if the data is not compressed.
+// The []byte is compressed at once so the body could be read again,
+// and the io.Reader is compressed when it's read.
+func (c *CompressionConfig) compress(data interface{}, headers http.Header) (interface{}, bool, error) {
+	// 已指定encoding的数据不再压缩
+	if headers.Get(headerContentEncoding) != "" {
+		return data, false, nil
+	}
+	switch data := data.(type) {
+	case []byte:
+		if len(data) == 0 || len(data) < c.MinLength {
+			return data, false, nil
+		}
+		buf := new(bytes.Buffer)
+		w, err := c.newWriter(buf)
+		if err != nil {
+			return nil, false, err
+		}
+		_, err = w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		headers.Set(headerContentEncoding, c.encoding())
+		return buf.Bytes(), true, nil
+	case io.Reader:
+		closer, _ := data.(io.Closer)
+		// 先读取min length的数据，判断是否需要压缩
+		if c.MinLength > 0 {
+			peek := make([]byte, c.MinLength)
+			n, err := io.ReadFull(data, peek)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				if closer != nil {
+					_ = closer.Close()
+				}
+				return peek[:n], false, nil
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			data = io.MultiReader(bytes.NewReader(peek), data)
+		}
+		buf := new(bytes.Buffer)
+		w, err := c.newWriter(buf)
+		if err != nil {
+			return nil, false, err
+		}
+		headers.Set(headerContentEncoding, c.encoding())
+		return &compressReader{
+			src:    data,
+			closer: closer,
+			buf:    buf,
+			w:      w,
+			chunk:  make([]byte, compressionChunkSize),
+		}, true, nil
+	}
+	return data, false, nil
+}
+
+// Read reads the compressed data, the source is read chunk by chunk
+func (r *compressReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		n, err := r.src.Read(r.chunk)
+		if n > 0 {
+			if _, e := r.w.Write(r.chunk[:n]); e != nil {
+				err = e
+			}
+		}
+		if err == io.EOF {
+			// 读取完成后flush剩余的数据
+			err = r.w.Close()
+			if err == nil {
+				err = io.EOF
+			}
+		}
+		r.err = err
+	}
+	if r.buf.Len() != 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
+
+// Close closes the compression writer and the source reader(if it's an io.Closer)
+func (r *compressReader) Close() error {
+	// 未读取完成时需要关闭压缩的writer释放资源
+	if r.err == nil {
+		r.err = io.ErrClosedPipe
+		_ = r.w.Close()
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func decompressForTest(encoding string, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case CompressionBr:
+		r = brotli.NewReader(bytes.NewReader(data))
+	case CompressionZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return data, nil
+	}
+	return io.ReadAll(r)
+}
+
+func TestCompressionConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte(strings.Repeat(`{"name":"tree"}`, 10000))
+	for _, encoding := range []string{
+		CompressionGzip,
+		CompressionBr,
+		CompressionZstd,
+	} {
+		c := &CompressionConfig{
+			Encoding: encoding,
+			Level:    3,
+		}
+		// []byte
+		headers := http.Header{}
+		result, compressed, err := c.compress(data, headers)
+		assert.Nil(err)
+		assert.True(compressed)
+		assert.Equal(encoding, headers.Get(headerContentEncoding))
+		buf, ok := result.([]byte)
+		assert.True(ok)
+		assert.True(len(buf) < len(data))
+		buf, err = decompressForTest(encoding, buf)
+		assert.Nil(err)
+		assert.Equal(data, buf)
+
+		// io.Reader
+		headers = http.Header{}
+		result, compressed, err = c.compress(io.NopCloser(bytes.NewReader(data)), headers)
+		assert.Nil(err)
+		assert.True(compressed)
+		assert.Equal(encoding, headers.Get(headerContentEncoding))
+		r, ok := result.(io.ReadCloser)
+		assert.True(ok)
+		buf, err = io.ReadAll(r)
+		assert.Nil(err)
+		assert.Nil(r.Close())
+		buf, err = decompressForTest(encoding, buf)
+		assert.Nil(err)
+		assert.Equal(data, buf)
+	}
+
+	// 默认为gzip
+	c := &CompressionConfig{
+		MinLength: 100,
+	}
+	headers := http.Header{}
+	result, compressed, err := c.compress(strings.NewReader(strings.Repeat("a", 100)), headers)
+	assert.Nil(err)
+	assert.True(compressed)
+	assert.Equal(CompressionGzip, headers.Get(headerContentEncoding))
+	buf, _ := io.ReadAll(result.(io.Reader))
+	buf, err = decompressForTest(CompressionGzip, buf)
+	assert.Nil(err)
+	assert.Equal(strings.Repeat("a", 100), string(buf))
+
+	// 小于min length
+	headers = http.Header{}
+	result, compressed, err = c.compress([]byte("abc"), headers)
+	assert.Nil(err)
+	assert.False(compressed)
+	assert.Equal([]byte("abc"), result)
+	result, compressed, err = c.compress(strings.NewReader("abc"), headers)
+	assert.Nil(err)
+	assert.False(compressed)
+	assert.Equal([]byte("abc"), result)
+	assert.Empty(headers.Get(headerContentEncoding))
+
+	// 已设置encoding
+	headers.Set(headerContentEncoding, "deflate")
+	result, compressed, err = c.compress(data, headers)
+	assert.Nil(err)
+	assert.False(compressed)
+	assert.Equal(data, result)
+
+	_, _, err = (&CompressionConfig{
+		Encoding: "deflate",
+	}).compress(data, http.Header{})
+	assert.Equal(ErrCompressionUnsupported, err)
+}
+
+func TestRequestBodyCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		body, err := decompressForTest(r.Header.Get(headerContentEncoding), body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("X-Content-Encoding", r.Header.Get(headerContentEncoding))
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+		Compression: &CompressionConfig{
+			Encoding:  CompressionZstd,
+			MinLength: 20,
+		},
+	})
+	resp, err := ins.Post("/", map[string]string{
+		"name": strings.Repeat("tree", 10),
+	})
+	assert.Nil(err)
+	assert.Equal(CompressionZstd, resp.Headers.Get("X-Content-Encoding"))
+	assert.Equal(`{"name":"`+strings.Repeat("tree", 10)+`"}`, string(resp.Data))
+	// curl使用未压缩的数据
+	assert.Equal(`curl -XPOST -d '{"name":"`+strings.Repeat("tree", 10)+`"}' -H 'Content-Type:application/json;charset=utf-8' '`+server.URL+`/'`, resp.Config.CURL())
+
+	// 小于min length
+	resp, err = ins.Post("/", "abc")
+	assert.Nil(err)
+	assert.Equal("", resp.Headers.Get("X-Content-Encoding"))
+	assert.Equal("abc", string(resp.Data))
+
+	// io.Reader
+	resp, err = ins.Put("/", strings.NewReader(strings.Repeat("a", 1024)))
+	assert.Nil(err)
+	assert.Equal(CompressionZstd, resp.Headers.Get("X-Content-Encoding"))
+	assert.Equal(strings.Repeat("a", 1024), string(resp.Data))
+
+	// 重新生成时依然压缩
+	conf := &Config{
+		Method:           http.MethodPost,
+		Headers:          http.Header{},
+		TransformRequest: DefaultTransformRequest,
+		Body:             strings.Repeat("a", 1024),
+		Compression:      &CompressionConfig{},
+	}
+	for i := 0; i < 2; i++ {
+		r, err := conf.getRequestBody()
+		assert.Nil(err)
+		assert.Equal(CompressionGzip, conf.Headers.Get(headerContentEncoding))
+		buf, _ := io.ReadAll(r)
+		buf, err = decompressForTest(CompressionGzip, buf)
+		assert.Nil(err)
+		assert.Equal(strings.Repeat("a", 1024), string(buf))
+	}
+}
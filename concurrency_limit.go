@@ -0,0 +1,140 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrTooManyHostRequests = errors.New("too many request of the host")
+var ErrTooManyRouteRequests = errors.New("too many request of the route")
+
+type (
+	// ConcurrencyLimitConfig config of per host and per route concurrency limits
+	ConcurrencyLimitConfig struct {
+		// PerHost max concurrency of each host, no limit if it's 0
+		PerHost uint32
+		// Hosts max concurrency of the host(with port if it's set in url),
+		// it overrides PerHost
+		Hosts map[string]uint32
+		// Routes max concurrency of the route, no limit if it's 0
+		Routes map[string]uint32
+	}
+
+	concurrencyLimiter struct {
+		mutex  sync.Mutex
+		conf   *ConcurrencyLimitConfig
+		hosts  map[string]uint32
+		routes map[string]uint32
+	}
+)
+
+func newConcurrencyLimiter(conf *ConcurrencyLimitConfig) *concurrencyLimiter {
+	hosts := make(map[string]uint32)
+	for host, limit := range conf.Hosts {
+		hosts[strings.ToLower(host)] = limit
+	}
+	return &concurrencyLimiter{
+		conf: &ConcurrencyLimitConfig{
+			PerHost: conf.PerHost,
+			Hosts:   hosts,
+			Routes:  conf.Routes,
+		},
+		hosts:  make(map[string]uint32),
+		routes: make(map[string]uint32),
+	}
+}
+
+func (cl *concurrencyLimiter) hostLimit(host string) uint32 {
+	if limit, ok := cl.conf.Hosts[host]; ok {
+		return limit
+	}
+	return cl.conf.PerHost
+}
+
+// decreaseCount decreases the count of key, the key is deleted if the count is 0
+func decreaseCount(counts map[string]uint32, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+// acquire increases the counts of host and route, it returns error if
+// the count is gt limit, the release function should be called when the request is done
+func (cl *concurrencyLimiter) acquire(config *Config) (func(), error) {
+	if cl == nil {
+		return func() {}, nil
+	}
+	host := strings.ToLower(CircuitKeyByHost(config))
+	route := config.getRoute()
+	routeLimit := cl.conf.Routes[route]
+	routeLimited := routeLimit != 0
+
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	if limit := cl.hostLimit(host); limit != 0 && cl.hosts[host] >= limit {
+		return nil, ErrTooManyHostRequests
+	}
+	// route仅统计有配置限制的
+	if routeLimited && cl.routes[route] >= routeLimit {
+		return nil, ErrTooManyRouteRequests
+	}
+	cl.hosts[host]++
+	if routeLimited {
+		cl.routes[route]++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cl.mutex.Lock()
+			defer cl.mutex.Unlock()
+			decreaseCount(cl.hosts, host)
+			if routeLimited {
+				decreaseCount(cl.routes, route)
+			}
+		})
+	}, nil
+}
+
+// copyCounts returns the copy of counts
+func (cl *concurrencyLimiter) copyCounts(counts map[string]uint32) map[string]uint32 {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	result := make(map[string]uint32, len(counts))
+	for key, value := range counts {
+		result[key] = value
+	}
+	return result
+}
+
+// hostCounts returns the concurrency of each host
+func (cl *concurrencyLimiter) hostCounts() map[string]uint32 {
+	if cl == nil {
+		return map[string]uint32{}
+	}
+	return cl.copyCounts(cl.hosts)
+}
+
+// routeCounts returns the concurrency of each limited route
+func (cl *concurrencyLimiter) routeCounts() map[string]uint32 {
+	if cl == nil {
+		return map[string]uint32{}
+	}
+	return cl.copyCounts(cl.routes)
+}
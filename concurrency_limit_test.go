@@ -0,0 +1,116 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	received := make(chan struct{})
+	done := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			received <- struct{}{}
+			<-done
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	otherServer := httptest.NewServer(handler)
+	defer otherServer.Close()
+	host := server.Listener.Addr().String()
+	otherURL, _ := url.Parse(otherServer.URL)
+
+	// 等待请求已到达服务端
+	block := func(ins *Instance, rawURL string) chan error {
+		result := make(chan error, 1)
+		go func() {
+			_, err := ins.Get(rawURL)
+			result <- err
+		}()
+		<-received
+		return result
+	}
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+		ConcurrencyLimit: &ConcurrencyLimitConfig{
+			PerHost: 1,
+			Hosts: map[string]uint32{
+				otherURL.Host: 2,
+			},
+		},
+	})
+	result := block(ins, "/block")
+	assert.Equal(map[string]uint32{
+		host: 1,
+	}, ins.GetHostConcurrency())
+	_, err := ins.Get("/ok")
+	assert.Equal(ErrTooManyHostRequests, err)
+	// 其它host不受影响
+	otherResult := block(ins, otherServer.URL+"/block")
+	resp, err := ins.Get(otherServer.URL + "/ok")
+	assert.Nil(err)
+	assert.Equal("ok", string(resp.Data))
+	assert.Equal(map[string]uint32{
+		host:          1,
+		otherURL.Host: 1,
+	}, ins.GetHostConcurrency())
+	assert.Equal(uint32(2), ins.GetConcurrency())
+	done <- struct{}{}
+	done <- struct{}{}
+	assert.Nil(<-result)
+	assert.Nil(<-otherResult)
+	assert.Empty(ins.GetHostConcurrency())
+	assert.Equal(uint32(0), ins.GetConcurrency())
+
+	// route的限制
+	ins = NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+		ConcurrencyLimit: &ConcurrencyLimitConfig{
+			Routes: map[string]uint32{
+				"/block": 1,
+			},
+		},
+	})
+	result = block(ins, "/block")
+	assert.Equal(map[string]uint32{
+		"/block": 1,
+	}, ins.GetRouteConcurrency())
+	_, err = ins.Get(otherServer.URL + "/block")
+	assert.Equal(ErrTooManyRouteRequests, err)
+	_, err = ins.Get("/ok")
+	assert.Nil(err)
+	assert.Equal(map[string]uint32{
+		host: 1,
+	}, ins.GetHostConcurrency())
+	done <- struct{}{}
+	assert.Nil(<-result)
+	assert.Empty(ins.GetRouteConcurrency())
+
+	// 未配置
+	ins = NewInstance(nil)
+	assert.Empty(ins.GetHostConcurrency())
+	assert.Empty(ins.GetRouteConcurrency())
+}
@@ -72,13 +72,40 @@ type (
 
 		// Body the request body
 		Body interface{}
+		// Compression compression config of request body, the body is not compressed if it's nil
+		Compression *CompressionConfig
 
 		// Concurrency current amount handling request of instance
 		Concurrency uint32
+		// Priority priority of request waiting in the queue, the higher priority first
+		Priority int
 
 		// Timeout request timeout
 		Timeout time.Duration
 
+		// Stream the response body is not read into Data but exposed as Response.Body,
+		// which must be closed by the caller
+		Stream bool
+
+		// ValidateStatus validates the status of response,
+		// the request fails with *HTTPError if it returns false
+		ValidateStatus ValidateStatus
+
+		// OnUploadProgress on progress event of sending request body
+		OnUploadProgress OnProgress
+		// OnDownloadProgress on progress event of reading response body(only for default adapter)
+		OnDownloadProgress OnProgress
+		// ProgressInterval the interval of progress event, default is 200ms
+		ProgressInterval time.Duration
+		// BandwidthLimit the max bytes per second of sending request body and
+		// reading response body, no limit if it's 0
+		BandwidthLimit int64
+
+		// Retry retry policy of request
+		Retry *RetryPolicy
+		// Attempts the count of attempts which have been done
+		Attempts int
+
 		// Context context
 		Context context.Context
 
@@ -100,7 +127,15 @@ type (
 
 		HTTPTrace   *HT.HTTPTrace
 		enableTrace bool
-		data        map[string]interface{}
+		// bodyCompressed the request body is compressed by compression config
+		bodyCompressed bool
+		// noTimeout the timeout of instance is not used
+		noTimeout bool
+		// bandwidth the bandwidth bucket of request
+		bandwidth *tokenBucket
+		// bandwidthBuckets the bandwidth buckets used by adapter
+		bandwidthBuckets []*tokenBucket
+		data             map[string]interface{}
 	}
 	// InstanceConfig config of instance
 	InstanceConfig struct {
@@ -115,6 +150,13 @@ type (
 		Headers http.Header
 		// Timeout request timeout
 		Timeout time.Duration
+		// Retry retry policy of request
+		Retry *RetryPolicy
+		// Compression compression config of request body, the body is not compressed if it's nil
+		Compression *CompressionConfig
+		// ValidateStatus validates the status of response,
+		// the request fails with *HTTPError if it returns false
+		ValidateStatus ValidateStatus
 
 		// Client http client
 		Client *http.Client
@@ -123,6 +165,24 @@ type (
 		// MaxConcurrency max concurrency for instance
 		// If lt 0, all request will be fail
 		MaxConcurrency int32
+		// QueueSize max count of requests waiting in the queue when max concurrency is reached,
+		// the request fails with ErrTooManyRequests if queue is full or disabled(0)
+		QueueSize int
+		// QueueTimeout max wait time of request in the queue, no limit if it's 0
+		QueueTimeout time.Duration
+		// ConcurrencyLimit per host and per route concurrency limits, it's disabled if it's nil
+		ConcurrencyLimit *ConcurrencyLimitConfig
+		// Cache response cache for get request, cache is disabled if it's nil
+		Cache Cache
+		// RateLimiter rate limiter config, rate limiter is disabled if it's nil
+		RateLimiter *RateLimiterConfig
+		// CircuitBreaker circuit breaker config, circuit breaker is disabled if it's nil
+		CircuitBreaker *CircuitBreakerConfig
+		// EnableSession enable session, the instance has its own cookie jar
+		EnableSession bool
+		// BandwidthLimit the max bytes per second of sending request body and reading
+		// response body, it's shared by all requests of instance, no limit if it's 0
+		BandwidthLimit int64
 
 		// RequestInterceptors request interceptor list
 		RequestInterceptors []RequestInterceptor
@@ -137,11 +197,19 @@ type (
 		OnDone OnDone
 		// OnBeforeNewRequest on request create event
 		OnBeforeNewRequest OnBeforeNewRequest
+		// OnCircuitStateChange on circuit state change event
+		OnCircuitStateChange OnCircuitStateChange
+
+		onCircuitStateChanges CircuitStateChangeListeners
+		// adapterWrappers wrap the adapter of each request(e.g. the retry of 401 for auth),
+		// they're applied to the adapter of request, instance or mock
+		adapterWrappers []func(Adapter) Adapter
 	}
 )
 
 var ErrRequestDataTypeInvalid = errors.New("request data type is not supported")
 var ErrRequestIsForbidden = errors.New("request is forbidden")
+var ErrRequestBodyNotReplayable = errors.New("request body can not be read again")
 
 func (bc *baseConfig) AddErrorListener(listeners ...OnError) {
 	bc.onErrors = append(bc.onErrors, listeners...)
@@ -166,6 +234,31 @@ func (bc *baseConfig) PrependBeforeNewRequestListener(listeners ...OnBeforeNewRe
 	bc.onBeforeNewRequests = append(listeners, bc.onBeforeNewRequests...)
 }
 
+// addAdapterWrapper adds the wrapper of adapter, the later one wraps the former
+func (conf *InstanceConfig) addAdapterWrapper(fn func(Adapter) Adapter) {
+	conf.adapterWrappers = append(conf.adapterWrappers, fn)
+}
+
+func (conf *InstanceConfig) AddCircuitStateChangeListener(listeners ...OnCircuitStateChange) {
+	conf.onCircuitStateChanges = append(conf.onCircuitStateChanges, listeners...)
+}
+
+func (conf *InstanceConfig) PrependCircuitStateChangeListener(listeners ...OnCircuitStateChange) {
+	conf.onCircuitStateChanges = append(listeners, conf.onCircuitStateChanges...)
+}
+
+func (conf *InstanceConfig) doCircuitStateChange(key string, from, to CircuitState) {
+	fns := conf.onCircuitStateChanges
+	if conf.OnCircuitStateChange != nil {
+		fns = append([]OnCircuitStateChange{
+			conf.OnCircuitStateChange,
+		}, fns...)
+	}
+	for _, fn := range fns {
+		fn(key, from, to)
+	}
+}
+
 func (conf *Config) doBeforeNewRequest() error {
 	fns := conf.onBeforeNewRequests
 	if conf.OnBeforeNewRequest != nil {
@@ -358,6 +451,19 @@ func urlJoin(basicURL, url string) string {
 	return basicURL + url
 }
 
+// getRoute returns the route of request config,
+// it returns the path of url if route is not set
+func (conf *Config) getRoute() string {
+	if conf.Route != "" {
+		return conf.Route
+	}
+	urlInfo, _ := url.Parse(conf.URL)
+	if urlInfo != nil {
+		return urlInfo.Path
+	}
+	return conf.URL
+}
+
 // GetURL generate the url of request config
 func (conf *Config) GetURL() string {
 	url := urlJoin(conf.BaseURL, conf.URL)
@@ -392,8 +498,13 @@ func isNeedToTransformRequestBody(method string) bool {
 	return false
 }
 
-// getRequestBody get request body
+// getRequestBody get request body, it's compressed if compression is set
 func (conf *Config) getRequestBody() (r io.Reader, err error) {
+	return conf.newRequestBody(true)
+}
+
+// newRequestBody transforms the request body, and compresses it if compress is true
+func (conf *Config) newRequestBody(compress bool) (r io.Reader, err error) {
 	if conf.Body == nil || !isNeedToTransformRequestBody(conf.Method) {
 		return
 	}
@@ -406,6 +517,17 @@ func (conf *Config) getRequestBody() (r io.Reader, err error) {
 		}
 		data = buf
 	}
+	// 压缩在数据转换之后
+	if compress && conf.Compression != nil {
+		// 重试时删除上次压缩所设置的encoding
+		if conf.bodyCompressed {
+			conf.Headers.Del(headerContentEncoding)
+		}
+		data, conf.bodyCompressed, err = conf.Compression.compress(data, conf.Headers)
+		if err != nil {
+			return
+		}
+	}
 	r, ok := data.(io.Reader)
 	if ok {
 		return r, nil
@@ -419,6 +541,28 @@ func (conf *Config) getRequestBody() (r io.Reader, err error) {
 	return
 }
 
+// replayRequest clones the request with the same body for sending it again,
+// it returns false if the body can't be read again(e.g. io.Reader)
+func (conf *Config) replayRequest() (*http.Request, bool) {
+	req := conf.Request
+	if req == nil {
+		return nil, false
+	}
+	newReq := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return newReq, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	newReq.Body = body
+	return newReq, true
+}
+
 // CURL convert config to curl
 func (conf *Config) CURL() string {
 	builder := new(strings.Builder)
@@ -428,13 +572,18 @@ func (conf *Config) CURL() string {
 	}
 	builder.WriteString(fmt.Sprintf("curl -X%s ", method))
 
-	r, _ := conf.getRequestBody()
+	// 压缩的数据无法直接输出，因此使用未压缩的数据
+	r, _ := conf.newRequestBody(false)
 	if r != nil {
 		buf, _ := io.ReadAll(r)
 		builder.WriteString(fmt.Sprintf(`-d '%s' `, strings.ReplaceAll(string(buf), "'", "\u0027")))
 	}
 
 	for key, values := range conf.Headers {
+		// 压缩时设置的Content-Encoding忽略
+		if conf.bodyCompressed && strings.EqualFold(key, headerContentEncoding) {
+			continue
+		}
 		for _, value := range values {
 			builder.WriteString(fmt.Sprintf(`-H '%s:%s' `, key, value))
 		}
@@ -16,6 +16,7 @@ package axios
 
 import (
 	"bytes"
+	"io"
 	"net/http"
 	"net/url"
 	"testing"
@@ -143,6 +144,30 @@ func TestGetRequestBody(t *testing.T) {
 	assert.Equal(r, body)
 }
 
+func TestReplayRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	config := &Config{}
+	_, ok := config.replayRequest()
+	assert.False(ok)
+
+	config.Request, _ = http.NewRequest("POST", "/", bytes.NewReader([]byte("abc")))
+	_, _ = io.ReadAll(config.Request.Body)
+	req, ok := config.replayRequest()
+	assert.True(ok)
+	buf, _ := io.ReadAll(req.Body)
+	assert.Equal("abc", string(buf))
+
+	config.Request, _ = http.NewRequest("GET", "/", nil)
+	_, ok = config.replayRequest()
+	assert.True(ok)
+
+	// 普通的reader无法重放
+	config.Request, _ = http.NewRequest("POST", "/", io.LimitReader(bytes.NewReader([]byte("abc")), 3))
+	_, ok = config.replayRequest()
+	assert.False(ok)
+}
+
 func TestCURL(t *testing.T) {
 	assert := assert.New(t)
 	query := make(url.Values)
@@ -0,0 +1,116 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DigestSHA256 sha-256 algorithm of content digest
+	DigestSHA256 = "sha-256"
+	// DigestSHA512 sha-512 algorithm of content digest
+	DigestSHA512 = "sha-512"
+
+	headerContentDigest = "Content-Digest"
+)
+
+var (
+	ErrContentDigestMissing     = errors.New("content digest is missing")
+	ErrContentDigestUnsupported = errors.New("content digest algorithm is not supported")
+	ErrContentDigestMismatch    = errors.New("content digest is mismatch")
+	// ErrContentDigestStream the content digest of stream response can't be verified
+	ErrContentDigestStream = errors.New("content digest of stream response can't be verified")
+)
+
+var contentDigestHashes = map[string]func() hash.Hash{
+	DigestSHA256: sha256.New,
+	DigestSHA512: sha512.New,
+}
+
+// ContentDigest returns the value of Content-Digest header(rfc 9530)
+func ContentDigest(algorithm string, r io.Reader) (string, error) {
+	fn, ok := contentDigestHashes[algorithm]
+	if !ok {
+		return "", ErrContentDigestUnsupported
+	}
+	h := fn()
+	if r != nil {
+		_, err := io.Copy(h, r)
+		if err != nil {
+			return "", err
+		}
+	}
+	return algorithm + "=:" + base64.StdEncoding.EncodeToString(h.Sum(nil)) + ":", nil
+}
+
+// VerifyContentDigest verifies the data with the Content-Digest header,
+// all supported algorithms of header are verified
+func VerifyContentDigest(header http.Header, data []byte) error {
+	value := strings.Join(header.Values(headerContentDigest), ",")
+	if value == "" {
+		return ErrContentDigestMissing
+	}
+	verified := false
+	for _, item := range strings.Split(value, ",") {
+		algorithm, digest, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		algorithm = strings.ToLower(algorithm)
+		if _, ok := contentDigestHashes[algorithm]; !ok {
+			continue
+		}
+		expected, _ := ContentDigest(algorithm, strings.NewReader(string(data)))
+		if subtle.ConstantTimeCompare([]byte(algorithm+"="+digest), []byte(expected)) != 1 {
+			return ErrContentDigestMismatch
+		}
+		verified = true
+	}
+	if !verified {
+		return ErrContentDigestUnsupported
+	}
+	return nil
+}
+
+// setContentDigest sets the Content-Digest header of request
+func setContentDigest(req *http.Request, algorithm string) error {
+	var r io.Reader
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return ErrRequestBodyNotReplayable
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		r = body
+	}
+	value, err := ContentDigest(algorithm, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerContentDigest, value)
+	return nil
+}
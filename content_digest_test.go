@@ -0,0 +1,64 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentDigest(t *testing.T) {
+	assert := assert.New(t)
+
+	// rfc 9530 example
+	data := `{"hello": "world"}`
+	value, err := ContentDigest(DigestSHA256, strings.NewReader(data))
+	assert.Nil(err)
+	assert.Equal("sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", value)
+
+	_, err = ContentDigest("md5", nil)
+	assert.Equal(ErrContentDigestUnsupported, err)
+
+	header := http.Header{}
+	assert.Equal(ErrContentDigestMissing, VerifyContentDigest(header, []byte(data)))
+
+	sha512Value, _ := ContentDigest(DigestSHA512, strings.NewReader(data))
+	header.Set(headerContentDigest, value+", "+sha512Value)
+	assert.Nil(VerifyContentDigest(header, []byte(data)))
+	assert.Equal(ErrContentDigestMismatch, VerifyContentDigest(header, []byte("abc")))
+
+	header.Set(headerContentDigest, "md5=:abc:")
+	assert.Equal(ErrContentDigestUnsupported, VerifyContentDigest(header, []byte(data)))
+}
+
+func TestSetContentDigest(t *testing.T) {
+	assert := assert.New(t)
+
+	req, _ := http.NewRequest("POST", "/", bytes.NewReader([]byte(`{"hello": "world"}`)))
+	assert.Nil(setContentDigest(req, DigestSHA256))
+	assert.Equal("sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", req.Header.Get(headerContentDigest))
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	assert.Nil(setContentDigest(req, DigestSHA256))
+	assert.Equal("sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:", req.Header.Get(headerContentDigest))
+
+	req, _ = http.NewRequest("POST", "/", io.LimitReader(strings.NewReader("abc"), 3))
+	assert.Equal(ErrRequestBodyNotReplayable, setContentDigest(req, DigestSHA256))
+}
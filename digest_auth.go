@@ -0,0 +1,413 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	// DigestAlgMD5 md5 algorithm of digest auth
+	DigestAlgMD5 = "MD5"
+	// DigestAlgMD5Sess md5-sess algorithm of digest auth
+	DigestAlgMD5Sess = "MD5-sess"
+	// DigestAlgSHA256 sha-256 algorithm of digest auth
+	DigestAlgSHA256 = "SHA-256"
+	// DigestAlgSHA256Sess sha-256-sess algorithm of digest auth
+	DigestAlgSHA256Sess = "SHA-256-sess"
+
+	headerWWWAuthenticate = "WWW-Authenticate"
+
+	digestQopAuth    = "auth"
+	digestQopAuthInt = "auth-int"
+)
+
+type (
+	// DigestAuthConfig config of digest auth
+	DigestAuthConfig struct {
+		// Username user name
+		Username string
+		// Password password
+		Password string
+	}
+
+	// digestChallenge the challenge of WWW-Authenticate
+	digestChallenge struct {
+		realm     string
+		nonce     string
+		opaque    string
+		algorithm string
+		qop       string
+		// count nonce count
+		count uint32
+	}
+
+	// DigestAuth digest access authentication(rfc 7616), the challenge
+	// of server is cached by host and realm for the subsequent requests
+	// of the same protection space
+	DigestAuth struct {
+		mutex      sync.Mutex
+		config     DigestAuthConfig
+		challenges map[string]*digestChallenge
+		// realms the realm of host and path directory
+		realms map[string]string
+		cnonce func() string
+	}
+)
+
+// NewDigestAuth creates a digest access authentication
+func NewDigestAuth(config DigestAuthConfig) *DigestAuth {
+	return &DigestAuth{
+		config:     config,
+		challenges: make(map[string]*digestChallenge),
+		realms:     make(map[string]string),
+		cnonce:     newDigestCnonce,
+	}
+}
+
+func newDigestCnonce() string {
+	data := make([]byte, 16)
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+// digestHash returns the hash function of algorithm
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "", DigestAlgMD5:
+		return md5.New
+	case DigestAlgSHA256:
+		return sha256.New
+	}
+	return nil
+}
+
+// parseDigestChallenge parses the digest challenge of WWW-Authenticate,
+// the strongest algorithm is selected if there are more than one challenge
+func parseDigestChallenge(header http.Header) *digestChallenge {
+	var result *digestChallenge
+	for _, value := range header.Values(headerWWWAuthenticate) {
+		for _, params := range splitAuthChallenges(value) {
+			if params["scheme"] != "digest" || params["nonce"] == "" {
+				continue
+			}
+			c := &digestChallenge{
+				realm:     params["realm"],
+				nonce:     params["nonce"],
+				opaque:    params["opaque"],
+				algorithm: params["algorithm"],
+			}
+			if digestHash(c.algorithm) == nil {
+				continue
+			}
+			if qop, ok := params["qop"]; ok {
+				for _, item := range strings.Split(qop, ",") {
+					item = strings.TrimSpace(item)
+					// 优先使用auth
+					if item == digestQopAuth || (item == digestQopAuthInt && c.qop == "") {
+						c.qop = item
+					}
+				}
+				if c.qop == "" {
+					continue
+				}
+			}
+			if result == nil || (!strings.HasPrefix(strings.ToUpper(result.algorithm), DigestAlgSHA256) &&
+				strings.HasPrefix(strings.ToUpper(c.algorithm), DigestAlgSHA256)) {
+				result = c
+			}
+		}
+	}
+	return result
+}
+
+// splitAuthChallenges splits the value of WWW-Authenticate to challenges,
+// the scheme is stored as lower case with key "scheme"
+func splitAuthChallenges(value string) []map[string]string {
+	result := make([]map[string]string, 0)
+	var current map[string]string
+	for value != "" {
+		value = strings.TrimLeft(value, " \t,")
+		if value == "" {
+			break
+		}
+		end := strings.IndexAny(value, "= \t,")
+		if end == -1 {
+			end = len(value)
+		}
+		token := value[:end]
+		value = strings.TrimLeft(value[end:], " \t")
+		// 无=则为新的scheme
+		if !strings.HasPrefix(value, "=") {
+			current = map[string]string{
+				"scheme": strings.ToLower(token),
+			}
+			result = append(result, current)
+			continue
+		}
+		value = strings.TrimLeft(value[1:], " \t")
+		var paramValue string
+		if strings.HasPrefix(value, `"`) {
+			buf := new(strings.Builder)
+			i := 1
+			for ; i < len(value) && value[i] != '"'; i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+				}
+				buf.WriteByte(value[i])
+			}
+			paramValue = buf.String()
+			if i < len(value) {
+				i++
+			}
+			value = value[i:]
+		} else {
+			end = strings.IndexAny(value, " \t,")
+			if end == -1 {
+				end = len(value)
+			}
+			paramValue = value[:end]
+			value = value[end:]
+		}
+		// token68或无scheme的参数忽略
+		if current != nil {
+			current[strings.ToLower(token)] = paramValue
+		}
+	}
+	return result
+}
+
+// requestBodyHash returns the hash of request body for auth-int
+func requestBodyHash(req *http.Request, fn func() hash.Hash) (string, error) {
+	h := fn()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", ErrRequestBodyNotReplayable
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		_, err = io.Copy(h, body)
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digestRealmKey returns the key of realm, it's the host with the directory of path
+func digestRealmKey(host, dir string) string {
+	return host + dir
+}
+
+// digestChallengeKey returns the key of challenge, it's the host with realm
+func digestChallengeKey(host, realm string) string {
+	return host + " " + realm
+}
+
+// requestDir returns the directory of request path
+func requestDir(req *http.Request) string {
+	p := req.URL.Path
+	if p == "" {
+		p = "/"
+	}
+	return path.Dir(p)
+}
+
+// getChallenge returns the cached challenge of request, the realm is
+// matched from the directory of path to the root(rfc 7617 protection space)
+func (d *DigestAuth) getChallenge(req *http.Request) *digestChallenge {
+	host := getRequestHost(req)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	dir := requestDir(req)
+	for {
+		if realm, ok := d.realms[digestRealmKey(host, dir)]; ok {
+			return d.challenges[digestChallengeKey(host, realm)]
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// setChallenge caches the challenge of request, the previous challenge
+// of the same realm is replaced
+func (d *DigestAuth) setChallenge(req *http.Request, c *digestChallenge) {
+	host := getRequestHost(req)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.realms[digestRealmKey(host, requestDir(req))] = c.realm
+	d.challenges[digestChallengeKey(host, c.realm)] = c
+}
+
+// authorization returns the authorization of request, the nonce count is increased
+func (d *DigestAuth) authorization(req *http.Request, c *digestChallenge) (string, error) {
+	fn := digestHash(c.algorithm)
+	h := func(values ...string) string {
+		hh := fn()
+		hh.Write([]byte(strings.Join(values, ":")))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+	uri := req.URL.RequestURI()
+	ha2 := h(req.Method, uri)
+	if c.qop == digestQopAuthInt {
+		bodyHash, err := requestBodyHash(req, fn)
+		if err != nil {
+			return "", err
+		}
+		ha2 = h(req.Method, uri, bodyHash)
+	}
+
+	d.mutex.Lock()
+	c.count++
+	nc := fmt.Sprintf("%08x", c.count)
+	d.mutex.Unlock()
+	cnonce := d.cnonce()
+
+	ha1 := h(d.config.Username, c.realm, d.config.Password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+	var response string
+	if c.qop == "" {
+		response = h(ha1, c.nonce, ha2)
+	} else {
+		response = h(ha1, c.nonce, nc, cnonce, c.qop, ha2)
+	}
+
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	params := []string{
+		"username=" + quote(d.config.Username),
+		"realm=" + quote(c.realm),
+		"nonce=" + quote(c.nonce),
+		"uri=" + quote(uri),
+	}
+	if c.algorithm != "" {
+		params = append(params, "algorithm="+c.algorithm)
+	}
+	params = append(params, "response="+quote(response))
+	if c.opaque != "" {
+		params = append(params, "opaque="+quote(c.opaque))
+	}
+	if c.qop != "" {
+		params = append(params, "qop="+c.qop, "nc="+nc, "cnonce="+quote(cnonce))
+	}
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// RequestInterceptor sets the authorization header of request if the
+// challenge of the protection space is cached
+func (d *DigestAuth) RequestInterceptor(config *Config) error {
+	req := config.Request
+	// 已设置认证信息则不处理
+	if req.Header.Get(headerAuthorization) != "" {
+		return nil
+	}
+	c := d.getChallenge(req)
+	if c == nil {
+		return nil
+	}
+	value, err := d.authorization(req, c)
+	// 如果无法计算(如body无法重复读取)，则等待服务端的challenge
+	if err != nil {
+		return nil
+	}
+	req.Header.Set(headerAuthorization, value)
+	return nil
+}
+
+// replayRequest clones the request with the body re-generated by getRequestBody,
+// it returns false if the body is an io.Reader which can't be read again
+func (d *DigestAuth) replayRequest(config *Config) (*http.Request, bool) {
+	if _, ok := config.Body.(io.Reader); ok {
+		return nil, false
+	}
+	r, err := config.getRequestBody()
+	if err != nil {
+		return nil, false
+	}
+	req := config.Request.Clone(config.Request.Context())
+	if r == nil {
+		req.Body = nil
+		req.GetBody = nil
+		return req, true
+	}
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false
+	}
+	req.ContentLength = int64(len(buf))
+	req.Body = io.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return req, true
+}
+
+// Attach attaches the digest auth to instance config, the request interceptor
+// is appended, and the adapter of each request(including mock) is wrapped
+// to answer the challenge of 401 response and retry once
+func (d *DigestAuth) Attach(conf *InstanceConfig) {
+	conf.RequestInterceptors = append(conf.RequestInterceptors, d.RequestInterceptor)
+	conf.addAdapterWrapper(d.wrapAdapter)
+}
+
+// wrapAdapter wraps the adapter to answer the challenge of 401 response and retry once
+func (d *DigestAuth) wrapAdapter(adapter Adapter) Adapter {
+	return func(config *Config) (*Response, error) {
+		resp, err := adapter(config)
+		if err != nil || resp == nil || resp.Status != http.StatusUnauthorized {
+			return resp, err
+		}
+		c := parseDigestChallenge(resp.Headers)
+		if c == nil {
+			return resp, nil
+		}
+		req, ok := d.replayRequest(config)
+		if !ok {
+			return resp, nil
+		}
+		d.setChallenge(req, c)
+		// 401的响应不再返回，关闭其body
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		value, err := d.authorization(req, c)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(headerAuthorization, value)
+		config.Request = req
+		return adapter(config)
+	}
+}
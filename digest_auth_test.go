@@ -0,0 +1,268 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDigestChallenge(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Add(headerWWWAuthenticate, `Basic realm="basic", Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=MD5, nonce="abc", opaque="xyz"`)
+	header.Add(headerWWWAuthenticate, `Digest realm="http-auth@example.org", qop="auth-int", algorithm=SHA-256, nonce="a\"b", opaque="xyz"`)
+	header.Add(headerWWWAuthenticate, `Digest realm="a", algorithm=SHA-512-256, nonce="abc"`)
+	c := parseDigestChallenge(header)
+	assert.Equal(&digestChallenge{
+		realm:     "http-auth@example.org",
+		nonce:     `a"b`,
+		opaque:    "xyz",
+		algorithm: DigestAlgSHA256,
+		qop:       digestQopAuthInt,
+	}, c)
+
+	header = http.Header{}
+	header.Set(headerWWWAuthenticate, `Digest realm="a", qop="auth, auth-int", nonce="abc"`)
+	c = parseDigestChallenge(header)
+	assert.Equal(digestQopAuth, c.qop)
+
+	header.Set(headerWWWAuthenticate, `Basic realm="a"`)
+	assert.Nil(parseDigestChallenge(header))
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	assert := assert.New(t)
+
+	// rfc 2617
+	d := NewDigestAuth(DigestAuthConfig{
+		Username: "Mufasa",
+		Password: "Circle Of Life",
+	})
+	d.cnonce = func() string {
+		return "0a4f113b"
+	}
+	req, _ := http.NewRequest("GET", "http://www.nowhere.org/dir/index.html", nil)
+	value, err := d.authorization(req, &digestChallenge{
+		realm:  "testrealm@host.com",
+		nonce:  "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		opaque: "5ccc069c403ebaf9f0171e9517f40e41",
+		qop:    digestQopAuth,
+	})
+	assert.Nil(err)
+	assert.Equal(`Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", uri="/dir/index.html", response="6629fae49393a05397450978507c4ef1", opaque="5ccc069c403ebaf9f0171e9517f40e41", qop=auth, nc=00000001, cnonce="0a4f113b"`, value)
+
+	// rfc 7616
+	d = NewDigestAuth(DigestAuthConfig{
+		Username: "Mufasa",
+		Password: "Circle of Life",
+	})
+	d.cnonce = func() string {
+		return "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+	}
+	req, _ = http.NewRequest("GET", "http://www.example.org/dir/index.html", nil)
+	for algorithm, response := range map[string]string{
+		DigestAlgMD5:    "8ca523f5e9506fed4657c9700eebdbec",
+		DigestAlgSHA256: "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1",
+	} {
+		value, err = d.authorization(req, &digestChallenge{
+			realm:     "http-auth@example.org",
+			nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+			opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+			algorithm: algorithm,
+			qop:       digestQopAuth,
+		})
+		assert.Nil(err)
+		assert.Contains(value, `response="`+response+`"`)
+		assert.Contains(value, "nc=00000001")
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	var challengeCount int32
+	var nonceCounts []string
+	// 模拟digest认证的服务端
+	server := func(config *Config) (*Response, error) {
+		req := config.Request
+		resp := &Response{
+			Status:  http.StatusOK,
+			Headers: http.Header{},
+		}
+		authorization := req.Header.Get(headerAuthorization)
+		if authorization == "" {
+			atomic.AddInt32(&challengeCount, 1)
+			resp.Status = http.StatusUnauthorized
+			resp.Headers.Set(headerWWWAuthenticate, `Digest realm="device", qop="auth-int", algorithm=SHA-256, nonce="nonce", opaque="opaque"`)
+			return resp, nil
+		}
+		params := splitAuthChallenges(authorization)[0]
+		d := NewDigestAuth(DigestAuthConfig{
+			Username: "tree",
+			Password: "xie",
+		})
+		d.cnonce = func() string {
+			return params["cnonce"]
+		}
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			qop:       params["qop"],
+		}
+		// nonce count由客户端递增
+		count, _ := strconv.ParseUint(params["nc"], 16, 32)
+		c.count = uint32(count) - 1
+		expected, err := d.authorization(req, c)
+		if err != nil {
+			return nil, err
+		}
+		if expected != authorization || params["realm"] != "device" {
+			resp.Status = http.StatusUnauthorized
+			return resp, nil
+		}
+		nonceCounts = append(nonceCounts, params["nc"])
+		resp.Data = []byte(`{"name":"tree"}`)
+		return resp, nil
+	}
+
+	conf := &InstanceConfig{
+		BaseURL: "http://device.local",
+		Adapter: server,
+	}
+	d := NewDigestAuth(DigestAuthConfig{
+		Username: "tree",
+		Password: "xie",
+	})
+	d.Attach(conf)
+	ins := NewInstance(conf)
+
+	resp, err := ins.Post("/users", map[string]string{
+		"name": "tree",
+	})
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.Status)
+	assert.Equal(int32(1), challengeCount)
+
+	// 使用缓存的nonce，无需再次认证
+	resp, err = ins.Get("/users/1")
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.Status)
+	assert.Equal(int32(1), challengeCount)
+	assert.Equal([]string{
+		"00000001",
+		"00000002",
+	}, nonceCounts)
+
+	// 请求的adapter同样处理401
+	resp, err = ins.Request(&Config{
+		URL:     "http://adapter.local/users/1",
+		Adapter: server,
+	})
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.Status)
+	assert.Equal(int32(2), challengeCount)
+
+	// io.Reader无法重放
+	resp, err = ins.Post("http://other.local/users", strings.NewReader("abc"))
+	assert.Nil(err)
+	assert.Equal(http.StatusUnauthorized, resp.Status)
+
+	// 密码错误
+	conf = &InstanceConfig{
+		BaseURL: "http://device.local",
+		Adapter: server,
+	}
+	NewDigestAuth(DigestAuthConfig{
+		Username: "tree",
+	}).Attach(conf)
+	resp, err = NewInstance(conf).Get("/users/1")
+	assert.Nil(err)
+	assert.Equal(http.StatusUnauthorized, resp.Status)
+	assert.Equal(int32(4), challengeCount)
+}
+
+func TestDigestAuthRealms(t *testing.T) {
+	assert := assert.New(t)
+
+	var challengeCount int32
+	var authorizations []string
+	// 同一host的不同路径使用不同的realm
+	server := func(config *Config) (*Response, error) {
+		req := config.Request
+		resp := &Response{
+			Status:  http.StatusOK,
+			Headers: http.Header{},
+		}
+		realm := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")[0]
+		authorization := req.Header.Get(headerAuthorization)
+		if authorization == "" || splitAuthChallenges(authorization)[0]["realm"] != realm {
+			atomic.AddInt32(&challengeCount, 1)
+			resp.Status = http.StatusUnauthorized
+			resp.Headers.Set(headerWWWAuthenticate, fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="%s-nonce"`, realm, realm))
+			return resp, nil
+		}
+		params := splitAuthChallenges(authorization)[0]
+		authorizations = append(authorizations, params["realm"]+":"+params["nonce"]+":"+params["nc"])
+		return resp, nil
+	}
+
+	conf := &InstanceConfig{
+		BaseURL: "http://device.local",
+		Adapter: server,
+	}
+	NewDigestAuth(DigestAuthConfig{
+		Username: "tree",
+		Password: "xie",
+	}).Attach(conf)
+	ins := NewInstance(conf)
+
+	for _, url := range []string{
+		"/a/users",
+		"/b/users",
+		"/a/users/1",
+		"/b/users/1",
+	} {
+		resp, err := ins.Get(url)
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.Status)
+	}
+	// 每个realm仅认证一次，且各自的nonce count递增
+	assert.Equal(int32(2), challengeCount)
+	assert.Equal([]string{
+		"a:a-nonce:00000001",
+		"b:b-nonce:00000001",
+		"a:a-nonce:00000002",
+		"b:b-nonce:00000002",
+	}, authorizations)
+
+	// adapter返回空响应
+	assert.NotPanics(func() {
+		resp, err := NewDigestAuth(DigestAuthConfig{}).wrapAdapter(func(config *Config) (*Response, error) {
+			return nil, nil
+		})(&Config{})
+		assert.Nil(resp)
+		assert.Nil(err)
+	})
+}
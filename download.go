@@ -0,0 +1,537 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	headerRange         = "Range"
+	headerIfRange       = "If-Range"
+	headerContentRange  = "Content-Range"
+	headerAcceptRanges  = "Accept-Ranges"
+	downloadTempSuffix  = ".download"
+	downloadStateSuffix = ".download.json"
+)
+
+var (
+	ErrDownloadChecksumMismatch = errors.New("checksum of download file is mismatch")
+	ErrDownloadResourceChanged  = errors.New("resource of download is changed")
+	ErrDownloadRangeInvalid     = errors.New("content range of download is invalid")
+)
+
+type (
+	// DownloadOptions options of download
+	DownloadOptions struct {
+		// Headers the headers of request
+		Headers http.Header
+		// Query query for request
+		Query url.Values
+		// Checksum the hex checksum of file, it's not verified if empty
+		Checksum string
+		// Hash the hash function of checksum, default is sha256
+		Hash func() hash.Hash
+		// Chunks the count of parallel chunks, the file is downloaded in
+		// parallel only if it's gt 1 and the server supports range requests
+		Chunks int
+		// Timeout the timeout of receiving response(not including reading the body),
+		// the timeout of instance is used if it's 0
+		Timeout time.Duration
+	}
+
+	// DownloadResult result of download
+	DownloadResult struct {
+		// Size the size of file
+		Size int64
+		// Resumed the size of data which is resumed from the previous download
+		Resumed int64
+		// ETag the etag of file
+		ETag string
+		// Chunks the count of chunks, it's 1 if the file is not downloaded in parallel
+		Chunks int
+	}
+
+	// downloadState the state of download, which is saved for resuming
+	downloadState struct {
+		URL          string           `json:"url"`
+		ETag         string           `json:"etag,omitempty"`
+		LastModified string           `json:"lastModified,omitempty"`
+		Size         int64            `json:"size,omitempty"`
+		Chunks       []*downloadChunk `json:"chunks,omitempty"`
+	}
+	downloadChunk struct {
+		Start   int64 `json:"start"`
+		End     int64 `json:"end"`
+		Written int64 `json:"written"`
+	}
+)
+
+// validator returns the validator of If-Range, the weak etag can't be used
+func (s *downloadState) validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+func (s *downloadState) setValidator(headers http.Header) {
+	s.ETag = headers.Get(headerETag)
+	s.LastModified = headers.Get(headerLastModified)
+}
+
+func loadDownloadState(file string) *downloadState {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	state := &downloadState{}
+	if json.Unmarshal(buf, state) != nil {
+		return nil
+	}
+	return state
+}
+
+func (s *downloadState) save(file string) error {
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf, 0600)
+}
+
+// parseContentRange parses the start and total size of Content-Range,
+// the total is -1 if it's unknown
+func parseContentRange(value string) (start, total int64, err error) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, ErrDownloadRangeInvalid
+	}
+	value = strings.TrimPrefix(value, "bytes ")
+	rangeValue, totalValue, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, 0, ErrDownloadRangeInvalid
+	}
+	startValue, _, ok := strings.Cut(rangeValue, "-")
+	if !ok {
+		return 0, 0, ErrDownloadRangeInvalid
+	}
+	start, err = strconv.ParseInt(startValue, 10, 64)
+	if err != nil {
+		return 0, 0, ErrDownloadRangeInvalid
+	}
+	total = -1
+	if totalValue != "*" {
+		total, err = strconv.ParseInt(totalValue, 10, 64)
+		if err != nil {
+			return 0, 0, ErrDownloadRangeInvalid
+		}
+	}
+	return start, total, nil
+}
+
+// getStatus returns the status of response or http error
+func getStatus(resp *Response, err error) int {
+	if resp != nil {
+		return resp.Status
+	}
+	he := &HTTPError{}
+	if errors.As(err, &he) {
+		return he.Status
+	}
+	return 0
+}
+
+func (ins *Instance) newDownloadConfig(rawURL string, opts *DownloadOptions) *Config {
+	headers := opts.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	// 避免服务端压缩后range不匹配
+	headers.Set(headerAcceptEncoding, "identity")
+	return &Config{
+		URL:     rawURL,
+		Headers: headers,
+		Query:   opts.Query,
+	}
+}
+
+// downloadSequential downloads the file to temp file, it's resumed from the size of temp file
+func (ins *Instance) downloadSequential(ctx context.Context, rawURL, tempFile, stateFile string, state *downloadState, opts *DownloadOptions) (*DownloadResult, error) {
+	f, err := os.OpenFile(tempFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	// 无校验值时无法判断文件是否有变化，从头下载
+	if offset != 0 && (state == nil || state.Chunks != nil || state.validator() == "") {
+		offset = 0
+	}
+	// 已下载完成
+	if offset != 0 && state.Size == offset {
+		return &DownloadResult{
+			Size:    offset,
+			Resumed: offset,
+			ETag:    state.ETag,
+			Chunks:  1,
+		}, nil
+	}
+
+	config := ins.newDownloadConfig(rawURL, opts)
+	if offset != 0 {
+		config.Headers.Set(headerRange, fmt.Sprintf("bytes=%d-", offset))
+		config.Headers.Set(headerIfRange, state.validator())
+	}
+	resp, err := ins.requestStream(ctx, config, opts.Timeout)
+	status := getStatus(resp, err)
+	// range无效则从头下载
+	if status == http.StatusRequestedRangeNotSatisfiable && offset != 0 {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		config = ins.newDownloadConfig(rawURL, opts)
+		offset = 0
+		resp, err = ins.requestStream(ctx, config, opts.Timeout)
+		status = getStatus(resp, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body == nil {
+		resp.Body = io.NopCloser(bytes.NewReader(resp.Data))
+	}
+	defer resp.Body.Close()
+	switch status {
+	case http.StatusPartialContent:
+		start, _, err := parseContentRange(resp.Headers.Get(headerContentRange))
+		if err != nil {
+			return nil, err
+		}
+		if start != offset {
+			return nil, ErrDownloadRangeInvalid
+		}
+	case http.StatusOK:
+		offset = 0
+	default:
+		return nil, newHTTPError(config, resp)
+	}
+	if offset == 0 {
+		err = f.Truncate(0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	newState := &downloadState{
+		URL: rawURL,
+	}
+	newState.setValidator(resp.Headers)
+	if status == http.StatusPartialContent {
+		_, newState.Size, _ = parseContentRange(resp.Headers.Get(headerContentRange))
+	} else if resp.Headers.Get(headerContentLength) != "" {
+		newState.Size, _ = strconv.ParseInt(resp.Headers.Get(headerContentLength), 10, 64)
+	}
+	if newState.Size < 0 {
+		newState.Size = 0
+	}
+	// 先保存状态，中断后可继续下载
+	err = newState.save(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(f, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	size := offset + n
+	if newState.Size != 0 && size != newState.Size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &DownloadResult{
+		Size:    size,
+		Resumed: offset,
+		ETag:    newState.ETag,
+		Chunks:  1,
+	}, nil
+}
+
+// newDownloadChunks splits the file to chunks
+func newDownloadChunks(size int64, count int) []*downloadChunk {
+	if int64(count) > size {
+		count = int(size)
+	}
+	chunkSize := size / int64(count)
+	chunks := make([]*downloadChunk, count)
+	for i := range chunks {
+		start := int64(i) * chunkSize
+		end := start + chunkSize - 1
+		if i == count-1 {
+			end = size - 1
+		}
+		chunks[i] = &downloadChunk{
+			Start: start,
+			End:   end,
+		}
+	}
+	return chunks
+}
+
+// downloadChunk downloads the range of chunk and writes it to file
+func (ins *Instance) downloadChunk(ctx context.Context, rawURL string, f *os.File, chunk *downloadChunk, validator string, opts *DownloadOptions) error {
+	written := atomic.LoadInt64(&chunk.Written)
+	start := chunk.Start + written
+	if start > chunk.End {
+		return nil
+	}
+	config := ins.newDownloadConfig(rawURL, opts)
+	config.Headers.Set(headerRange, fmt.Sprintf("bytes=%d-%d", start, chunk.End))
+	if validator != "" {
+		config.Headers.Set(headerIfRange, validator)
+	}
+	resp, err := ins.requestStream(ctx, config, opts.Timeout)
+	if err != nil {
+		return err
+	}
+	if resp.Body == nil {
+		resp.Body = io.NopCloser(bytes.NewReader(resp.Data))
+	}
+	defer resp.Body.Close()
+	// 返回完整数据则表示文件已变化
+	if resp.Status == http.StatusOK {
+		return ErrDownloadResourceChanged
+	}
+	if resp.Status != http.StatusPartialContent {
+		return newHTTPError(config, resp)
+	}
+	rangeStart, _, err := parseContentRange(resp.Headers.Get(headerContentRange))
+	if err != nil {
+		return err
+	}
+	if rangeStart != start {
+		return ErrDownloadRangeInvalid
+	}
+	buf := make([]byte, 32*1024)
+	for start <= chunk.End {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if int64(n) > chunk.End-start+1 {
+				n = int(chunk.End - start + 1)
+			}
+			_, e := f.WriteAt(buf[:n], start)
+			if e != nil {
+				return e
+			}
+			start += int64(n)
+			atomic.AddInt64(&chunk.Written, int64(n))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if start <= chunk.End {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// downloadParallel downloads the chunks of file in parallel
+func (ins *Instance) downloadParallel(ctx context.Context, rawURL, tempFile, stateFile string, state *downloadState, opts *DownloadOptions) (*DownloadResult, error) {
+	f, err := os.OpenFile(tempFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// 临时文件与状态不匹配则重新下载
+	if info.Size() != state.Size {
+		for _, chunk := range state.Chunks {
+			chunk.Written = 0
+		}
+		err = f.Truncate(state.Size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resumed := int64(0)
+	for _, chunk := range state.Chunks {
+		resumed += chunk.Written
+	}
+	err = state.save(stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	var once sync.Once
+	var chunkErr error
+	validator := state.validator()
+	for _, chunk := range state.Chunks {
+		wg.Add(1)
+		go func(chunk *downloadChunk) {
+			defer wg.Done()
+			err := ins.downloadChunk(ctx, rawURL, f, chunk, validator, opts)
+			if err != nil {
+				once.Do(func() {
+					chunkErr = err
+					cancel()
+				})
+			}
+		}(chunk)
+	}
+	wg.Wait()
+	// 保存各chunk的进度
+	if e := state.save(stateFile); e != nil && chunkErr == nil {
+		chunkErr = e
+	}
+	if chunkErr != nil {
+		return nil, chunkErr
+	}
+	return &DownloadResult{
+		Size:    state.Size,
+		Resumed: resumed,
+		ETag:    state.ETag,
+		Chunks:  len(state.Chunks),
+	}, nil
+}
+
+// newParallelDownloadState returns the state of parallel download if the server
+// supports range requests, otherwise it returns nil
+func (ins *Instance) newParallelDownloadState(ctx context.Context, rawURL string, opts *DownloadOptions) *downloadState {
+	config := ins.newDownloadConfig(rawURL, opts)
+	config.Method = http.MethodHead
+	config.Context = ctx
+	resp, err := ins.Request(config)
+	if err != nil || resp.Status != http.StatusOK ||
+		!strings.EqualFold(resp.Headers.Get(headerAcceptRanges), "bytes") {
+		return nil
+	}
+	size, _ := strconv.ParseInt(resp.Headers.Get(headerContentLength), 10, 64)
+	if size <= 0 {
+		return nil
+	}
+	state := &downloadState{
+		URL:  rawURL,
+		Size: size,
+	}
+	state.setValidator(resp.Headers)
+	state.Chunks = newDownloadChunks(size, opts.Chunks)
+	return state
+}
+
+// verifyChecksum verifies the checksum of file
+func verifyChecksum(file string, opts *DownloadOptions) error {
+	if opts.Checksum == "" {
+		return nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fn := opts.Hash
+	if fn == nil {
+		fn = sha256.New
+	}
+	h := fn()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), opts.Checksum) {
+		return ErrDownloadChecksumMismatch
+	}
+	return nil
+}
+
+// Download downloads the file of url to path. The data is written to a temp file
+// (path + ".download"), and the interrupted download is resumed with Range and If-Range
+// request. The temp file is renamed to path after the checksum is verified.
+func (ins *Instance) Download(ctx context.Context, rawURL, path string, opts *DownloadOptions) (*DownloadResult, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tempFile := path + downloadTempSuffix
+	stateFile := path + downloadStateSuffix
+	state := loadDownloadState(stateFile)
+	// 不同url的下载状态无效
+	if state != nil && state.URL != rawURL {
+		state = nil
+	}
+	var result *DownloadResult
+	var err error
+	// 服务端不支持range时使用顺序下载
+	if state == nil && opts.Chunks > 1 {
+		state = ins.newParallelDownloadState(ctx, rawURL, opts)
+	}
+	if state != nil && state.Chunks != nil {
+		result, err = ins.downloadParallel(ctx, rawURL, tempFile, stateFile, state, opts)
+	} else {
+		result, err = ins.downloadSequential(ctx, rawURL, tempFile, stateFile, state, opts)
+	}
+	// 文件已变化则删除临时文件，下次重新下载
+	if err == ErrDownloadResourceChanged {
+		_ = os.Remove(tempFile)
+		_ = os.Remove(stateFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = verifyChecksum(tempFile, opts)
+	if err != nil {
+		_ = os.Remove(tempFile)
+		_ = os.Remove(stateFile)
+		return nil, err
+	}
+	err = os.Rename(tempFile, path)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Remove(stateFile)
+	return result, nil
+}
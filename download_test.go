@@ -0,0 +1,264 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type downloadTestServer struct {
+	mutex   sync.Mutex
+	data    []byte
+	etag    string
+	ranges  []string
+	gets    int32
+	failing int32
+}
+
+func (s *downloadTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	data := s.data
+	etag := s.etag
+	if r.Method == http.MethodGet {
+		s.ranges = append(s.ranges, r.Header.Get(headerRange))
+	}
+	s.mutex.Unlock()
+	if r.Method == http.MethodGet {
+		atomic.AddInt32(&s.gets, 1)
+	}
+	// 仅返回一半的数据后中断
+	if r.Method == http.MethodGet && atomic.CompareAndSwapInt32(&s.failing, 1, 0) {
+		w.Header().Set(headerETag, etag)
+		w.Header().Set(headerContentLength, strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data[:len(data)/2])
+		return
+	}
+	w.Header().Set(headerETag, etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+func (s *downloadTestServer) reset(data []byte, etag string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data = data
+	s.etag = etag
+	s.ranges = nil
+	atomic.StoreInt32(&s.gets, 0)
+}
+
+func TestParseContentRange(t *testing.T) {
+	assert := assert.New(t)
+
+	start, total, err := parseContentRange("bytes 10-99/100")
+	assert.Nil(err)
+	assert.Equal(int64(10), start)
+	assert.Equal(int64(100), total)
+
+	start, total, err = parseContentRange("bytes 10-99/*")
+	assert.Nil(err)
+	assert.Equal(int64(10), start)
+	assert.Equal(int64(-1), total)
+
+	_, _, err = parseContentRange("bytes */100")
+	assert.Equal(ErrDownloadRangeInvalid, err)
+	_, _, err = parseContentRange("items 0-1/2")
+	assert.Equal(ErrDownloadRangeInvalid, err)
+}
+
+func TestNewDownloadChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]*downloadChunk{
+		{Start: 0, End: 32},
+		{Start: 33, End: 65},
+		{Start: 66, End: 99},
+	}, newDownloadChunks(100, 3))
+	assert.Equal([]*downloadChunk{
+		{Start: 0, End: 0},
+		{Start: 1, End: 1},
+	}, newDownloadChunks(2, 4))
+}
+
+func TestDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	s := &downloadTestServer{}
+	s.reset(data, `"v1"`)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	})
+	file := filepath.Join(t.TempDir(), "data.bin")
+
+	// 下载中断
+	atomic.StoreInt32(&s.failing, 1)
+	_, err := ins.Download(context.Background(), "/data", file, nil)
+	assert.NotNil(err)
+	info, err := os.Stat(file + downloadTempSuffix)
+	assert.Nil(err)
+	assert.Equal(int64(len(data)/2), info.Size())
+	_, err = os.Stat(file + downloadStateSuffix)
+	assert.Nil(err)
+
+	// 继续下载
+	result, err := ins.Download(context.Background(), "/data", file, &DownloadOptions{
+		Checksum: checksum,
+	})
+	assert.Nil(err)
+	assert.Equal(&DownloadResult{
+		Size:    int64(len(data)),
+		Resumed: int64(len(data) / 2),
+		ETag:    `"v1"`,
+		Chunks:  1,
+	}, result)
+	assert.Equal([]string{"", "bytes=50000-"}, s.ranges)
+	buf, err := os.ReadFile(file)
+	assert.Nil(err)
+	assert.Equal(data, buf)
+	_, err = os.Stat(file + downloadTempSuffix)
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(file + downloadStateSuffix)
+	assert.True(os.IsNotExist(err))
+
+	// 中断后文件已变化，重新下载
+	s.reset(data, `"v1"`)
+	atomic.StoreInt32(&s.failing, 1)
+	_, err = ins.Download(context.Background(), "/data", file, nil)
+	assert.NotNil(err)
+	newData := bytes.Repeat([]byte("abcdefghij"), 10000)
+	s.reset(newData, `"v2"`)
+	result, err = ins.Download(context.Background(), "/data", file, nil)
+	assert.Nil(err)
+	assert.Equal(int64(0), result.Resumed)
+	assert.Equal(`"v2"`, result.ETag)
+	buf, _ = os.ReadFile(file)
+	assert.Equal(newData, buf)
+
+	// 校验失败
+	s.reset(data, `"v1"`)
+	_, err = ins.Download(context.Background(), "/data", file, &DownloadOptions{
+		Checksum: checksum[1:] + "0",
+	})
+	assert.Equal(ErrDownloadChecksumMismatch, err)
+	_, err = os.Stat(file + downloadTempSuffix)
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(file + downloadStateSuffix)
+	assert.True(os.IsNotExist(err))
+	// 原有文件不受影响
+	buf, _ = os.ReadFile(file)
+	assert.Equal(newData, buf)
+}
+
+func TestDownloadParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	sum := sha256.Sum256(data)
+	s := &downloadTestServer{}
+	s.reset(data, `"v1"`)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	})
+	file := filepath.Join(t.TempDir(), "data.bin")
+	result, err := ins.Download(context.Background(), "/data", file, &DownloadOptions{
+		Chunks:   4,
+		Checksum: hex.EncodeToString(sum[:]),
+	})
+	assert.Nil(err)
+	assert.Equal(&DownloadResult{
+		Size:   int64(len(data)),
+		ETag:   `"v1"`,
+		Chunks: 4,
+	}, result)
+	assert.ElementsMatch([]string{
+		"bytes=0-24999",
+		"bytes=25000-49999",
+		"bytes=50000-74999",
+		"bytes=75000-99999",
+	}, s.ranges)
+	buf, _ := os.ReadFile(file)
+	assert.Equal(data, buf)
+
+	// 继续未完成的chunk
+	state := &downloadState{
+		URL:    "/data",
+		ETag:   `"v1"`,
+		Size:   int64(len(data)),
+		Chunks: newDownloadChunks(int64(len(data)), 2),
+	}
+	state.Chunks[0].Written = state.Chunks[0].End + 1
+	state.Chunks[1].Written = 10
+	assert.Nil(state.save(file + downloadStateSuffix))
+	tmp := make([]byte, len(data))
+	copy(tmp, data[:50010])
+	assert.Nil(os.WriteFile(file+downloadTempSuffix, tmp, 0600))
+	s.reset(data, `"v1"`)
+	result, err = ins.Download(context.Background(), "/data", file, &DownloadOptions{
+		Chunks: 4,
+	})
+	assert.Nil(err)
+	assert.Equal(int64(50010), result.Resumed)
+	assert.Equal(2, result.Chunks)
+	assert.Equal([]string{"bytes=50010-99999"}, s.ranges)
+	buf, _ = os.ReadFile(file)
+	assert.Equal(data, buf)
+
+	// 文件已变化
+	assert.Nil(state.save(file + downloadStateSuffix))
+	assert.Nil(os.WriteFile(file+downloadTempSuffix, tmp, 0600))
+	s.reset(data, `"v2"`)
+	_, err = ins.Download(context.Background(), "/data", file, nil)
+	assert.Equal(ErrDownloadResourceChanged, err)
+	_, err = os.Stat(file + downloadTempSuffix)
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(file + downloadStateSuffix)
+	assert.True(os.IsNotExist(err))
+
+	// 不支持range则顺序下载
+	noRangeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer noRangeServer.Close()
+	result, err = ins.Download(context.Background(), noRangeServer.URL, file, &DownloadOptions{
+		Chunks: 4,
+	})
+	assert.Nil(err)
+	assert.Equal(1, result.Chunks)
+	buf, _ = os.ReadFile(file)
+	assert.Equal(data, buf)
+}
@@ -1,9 +1,10 @@
 module github.com/vicanso/go-axios
 
-go 1.17
+go 1.18
 
 require (
 	github.com/andybalholm/brotli v1.1.1
+	github.com/klauspost/compress v1.17.2
 	github.com/stretchr/testify v1.9.0
 	github.com/vicanso/http-trace v1.2.0
 	gopkg.in/h2non/gock.v1 v1.1.2
@@ -0,0 +1,260 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const headerTrailer = "Trailer"
+
+type (
+	// handlerResponseWriter in-memory response writer,
+	// the data is written to a pipe which is read by the client
+	handlerResponseWriter struct {
+		mutex       sync.Mutex
+		head        bool
+		header      http.Header
+		snapshot    http.Header
+		status      int
+		wroteHeader bool
+		err         error
+		trailer     http.Header
+		ready       chan struct{}
+		readyOnce   sync.Once
+		pw          *io.PipeWriter
+	}
+
+	// handlerBody body of in-process response,
+	// the trailers are set to response when the body is read to EOF
+	handlerBody struct {
+		pr     *io.PipeReader
+		w      *handlerResponseWriter
+		res    *http.Response
+		cancel context.CancelFunc
+		once   sync.Once
+	}
+)
+
+func newHandlerResponseWriter(pw *io.PipeWriter, head bool) *handlerResponseWriter {
+	return &handlerResponseWriter{
+		head:   head,
+		header: make(http.Header),
+		ready:  make(chan struct{}),
+		pw:     pw,
+	}
+}
+
+func (w *handlerResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *handlerResponseWriter) WriteHeader(status int) {
+	// 忽略1xx的响应
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.snapshot = w.header.Clone()
+	w.readyOnce.Do(func() {
+		close(w.ready)
+	})
+}
+
+func (w *handlerResponseWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	wroteHeader := w.wroteHeader
+	w.mutex.Unlock()
+	if !wroteHeader {
+		if w.header.Get(headerContentType) == "" && len(p) != 0 {
+			w.header.Set(headerContentType, http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	// HEAD请求不返回数据
+	if w.head {
+		return len(p), nil
+	}
+	return w.pw.Write(p)
+}
+
+// Flush sends the header to client
+func (w *handlerResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// fail fails the response with error
+func (w *handlerResponseWriter) fail(err error) {
+	w.mutex.Lock()
+	w.err = err
+	w.mutex.Unlock()
+	w.readyOnce.Do(func() {
+		close(w.ready)
+	})
+	_ = w.pw.CloseWithError(err)
+}
+
+// finish collects the trailers and closes the pipe,
+// it's called after the handler returns
+func (w *handlerResponseWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+	trailer := make(http.Header)
+	for _, value := range w.snapshot.Values(headerTrailer) {
+		for _, key := range strings.Split(value, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if values, ok := w.header[key]; ok {
+				trailer[key] = values
+			}
+		}
+	}
+	for key, values := range w.header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(key, http.TrailerPrefix))] = values
+		}
+	}
+	w.trailer = trailer
+	_ = w.pw.Close()
+}
+
+func (b *handlerBody) Read(p []byte) (int, error) {
+	n, err := b.pr.Read(p)
+	if err == io.EOF {
+		b.once.Do(func() {
+			for key, values := range b.w.trailer {
+				b.res.Trailer[key] = values
+			}
+		})
+	}
+	return n, err
+}
+
+func (b *handlerBody) Close() error {
+	b.cancel()
+	return b.pr.Close()
+}
+
+// NewHandlerAdapter creates an adapter which dispatches the request to
+// the handler in process without sockets
+func NewHandlerAdapter(handler http.Handler) Adapter {
+	return func(config *Config) (*Response, error) {
+		req := config.Request
+		parent := req.Context()
+		ctx, cancel := context.WithCancel(parent)
+		r := req.Clone(ctx)
+		if r.Body == nil {
+			r.Body = http.NoBody
+		}
+		r.RequestURI = r.URL.RequestURI()
+		if r.RemoteAddr == "" {
+			r.RemoteAddr = "127.0.0.1:0"
+		}
+
+		pr, pw := io.Pipe()
+		w := newHandlerResponseWriter(pw, req.Method == http.MethodHead)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer cancel()
+			defer func() {
+				if e := recover(); e != nil {
+					w.fail(fmt.Errorf("handler panic: %v", e))
+				}
+			}()
+			handler.ServeHTTP(w, r)
+			w.finish()
+		}()
+		// 请求被取消时中断数据的读取
+		go func() {
+			select {
+			case <-parent.Done():
+				_ = pr.CloseWithError(parent.Err())
+			case <-done:
+			}
+		}()
+
+		select {
+		case <-w.ready:
+		case <-parent.Done():
+			cancel()
+			return nil, parent.Err()
+		}
+		w.mutex.Lock()
+		err := w.err
+		status := w.status
+		header := w.snapshot
+		w.mutex.Unlock()
+		if err != nil && header == nil {
+			cancel()
+			return nil, err
+		}
+
+		res := &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			ContentLength: -1,
+			Trailer:       make(http.Header),
+			Request:       req,
+		}
+		if value := header.Get(headerContentLength); value != "" {
+			res.ContentLength, _ = strconv.ParseInt(value, 10, 64)
+		}
+		// 与http.Client一致，声明的trailer先设置为nil
+		for _, value := range header.Values(headerTrailer) {
+			for _, key := range strings.Split(value, ",") {
+				res.Trailer[http.CanonicalHeaderKey(strings.TrimSpace(key))] = nil
+			}
+		}
+		res.Body = &handlerBody{
+			pr:     pr,
+			w:      w,
+			res:    res,
+			cancel: cancel,
+		}
+
+		resp := &Response{
+			Status:           status,
+			Headers:          header,
+			OriginalResponse: res,
+		}
+		// 流式响应由调用者读取数据
+		if config.Stream {
+			resp.Body = res.Body
+			return resp, nil
+		}
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data = data
+		return resp, nil
+	}
+}
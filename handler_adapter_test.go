@@ -0,0 +1,127 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerAdapter(t *testing.T) {
+	assert := assert.New(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentType, contentTypeJSON)
+		w.Header().Set("X-Token", r.Header.Get("X-Token"))
+		w.Header().Set(headerTrailer, "X-Checksum")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name":"tree"}`))
+		w.Header().Set("X-Checksum", "abc")
+		w.Header().Set(http.TrailerPrefix+"X-Count", "1")
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("abc")
+	})
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: "http://aslant.site",
+		Adapter: NewHandlerAdapter(mux),
+	})
+
+	resp, err := ins.Request(&Config{
+		URL: "/users/me",
+		Headers: http.Header{
+			"X-Token": []string{"abc"},
+		},
+	})
+	assert.Nil(err)
+	assert.Equal(http.StatusCreated, resp.Status)
+	assert.Equal("abc", resp.Headers.Get("X-Token"))
+	assert.Equal(`{"name":"tree"}`, string(resp.Data))
+	assert.Equal("abc", resp.OriginalResponse.Trailer.Get("X-Checksum"))
+	assert.Equal("1", resp.OriginalResponse.Trailer.Get("X-Count"))
+
+	resp, err = ins.Post("/echo", map[string]string{
+		"name": "tree",
+	})
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.Equal(`{"name":"tree"}`, string(resp.Data))
+
+	resp, err = ins.Head("/echo")
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.Empty(resp.Data)
+
+	resp, err = ins.Get("/none")
+	assert.Nil(err)
+	assert.Equal(404, resp.Status)
+
+	_, err = ins.Get("/panic")
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "handler panic: abc")
+}
+
+func TestHandlerAdapterStream(t *testing.T) {
+	assert := assert.New(t)
+
+	canceled := make(chan struct{})
+	ins := NewInstance(&InstanceConfig{
+		Adapter: NewHandlerAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/wait" {
+				<-r.Context().Done()
+				close(canceled)
+				return
+			}
+			for i := 0; i < 3; i++ {
+				_, err := w.Write([]byte("data\n"))
+				if err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+			}
+		})),
+	})
+
+	resp, err := ins.Request(&Config{
+		URL:    "/",
+		Stream: true,
+	})
+	assert.Nil(err)
+	data, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Nil(resp.Body.Close())
+	assert.Equal("data\ndata\ndata\n", string(data))
+
+	// 请求取消后handler的context也被取消
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ins.GetX(ctx, "/wait")
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		assert.Fail("handler context should be canceled")
+	}
+}
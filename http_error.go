@@ -0,0 +1,88 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// httpErrorBodyLimit max size of body kept in http error
+const httpErrorBodyLimit = 1024
+
+type (
+	// ValidateStatus returns true if the status of response is valid,
+	// otherwise the request fails with *HTTPError
+	ValidateStatus func(status int) bool
+
+	// HTTPError error of response whose status is invalid
+	HTTPError struct {
+		// Status status of response
+		Status int
+		// Headers headers of response
+		Headers http.Header
+		// Body data of response, it's truncated to 1KB
+		Body []byte
+		// Method method of request
+		Method string
+		// Route route of request
+		Route string
+		// URL url of request
+		URL string
+	}
+)
+
+// DefaultValidateStatus returns true if the status is 2xx
+func DefaultValidateStatus(status int) bool {
+	return isSuccessStatus(status)
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s fail, status: %d", e.Method, e.URL, e.Status)
+	if len(e.Body) != 0 {
+		msg += ", body: " + string(e.Body)
+	}
+	return msg
+}
+
+// newHTTPError creates http error from response
+func newHTTPError(config *Config, resp *Response) *HTTPError {
+	body := resp.Data
+	if len(body) > httpErrorBodyLimit {
+		body = body[:httpErrorBodyLimit]
+	}
+	data := make([]byte, len(body))
+	copy(data, body)
+	he := &HTTPError{
+		Status:  resp.Status,
+		Headers: resp.Headers,
+		Body:    data,
+		Method:  config.Method,
+		Route:   config.Route,
+		URL:     config.GetURL(),
+	}
+	if config.Request != nil {
+		he.URL = config.Request.URL.String()
+	}
+	return he
+}
+
+// validateStatus validates the status of response
+func (conf *Config) validateStatus(resp *Response) error {
+	if conf.ValidateStatus == nil || resp == nil || conf.ValidateStatus(resp.Status) {
+		return nil
+	}
+	return newHTTPError(conf, resp)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPError(t *testing.T) {
+	assert := assert.New(t)
+
+	he := newHTTPError(&Config{
+		Method: "GET",
+		Route:  "/users/:type",
+		URL:    "https://aslant.site/users/me",
+	}, &Response{
+		Status: 500,
+		Data:   bytes.Repeat([]byte("a"), 2048),
+	})
+	assert.Equal(500, he.Status)
+	assert.Equal(1024, len(he.Body))
+	assert.Equal("/users/:type", he.Route)
+	assert.Equal("https://aslant.site/users/me", he.URL)
+
+	he.Body = []byte("error")
+	assert.Equal("GET https://aslant.site/users/me fail, status: 500, body: error", he.Error())
+
+	assert.True(DefaultValidateStatus(204))
+	assert.False(DefaultValidateStatus(304))
+}
+
+func TestValidateStatus(t *testing.T) {
+	assert := assert.New(t)
+	domainErr := errors.New("domain error")
+	ins := NewInstance(&InstanceConfig{
+		BaseURL:        "https://aslant.site",
+		ValidateStatus: DefaultValidateStatus,
+		Adapter: func(config *Config) (*Response, error) {
+			status := 200
+			if config.Route == "/error" || config.Route == "/convert" {
+				status = 500
+			}
+			return &Response{
+				Status:  status,
+				Headers: http.Header{},
+				Data:    []byte("message"),
+			}, nil
+		},
+		OnError: func(err error, config *Config) error {
+			he := &HTTPError{}
+			if errors.As(err, &he) && he.Route == "/convert" {
+				return domainErr
+			}
+			return nil
+		},
+	})
+
+	_, err := ins.Get("/")
+	assert.Nil(err)
+
+	resp, err := ins.Get("/error")
+	he := &HTTPError{}
+	assert.True(errors.As(err, &he))
+	assert.Equal(500, he.Status)
+	assert.Equal("GET", he.Method)
+	assert.Equal("https://aslant.site/error", he.URL)
+	assert.Equal("message", string(he.Body))
+	assert.Equal(500, resp.Status)
+
+	_, err = ins.Get("/convert")
+	assert.Equal(domainErr, err)
+
+	// 单个请求可覆盖实例的配置
+	_, err = ins.Request(&Config{
+		URL: "/error",
+		ValidateStatus: func(status int) bool {
+			return status < 600
+		},
+	})
+	assert.Nil(err)
+}
@@ -33,13 +33,29 @@ type (
 
 	// Instance instance of axios
 	Instance struct {
-		Config      *InstanceConfig
-		concurrency uint32
+		Config             *InstanceConfig
+		concurrency        uint32
+		queue              requestQueue
+		circuitBreaker     *circuitBreaker
+		rateLimiter        *rateLimiter
+		cookieJar          *CookieJar
+		bandwidth          *tokenBucket
+		concurrencyLimiter *concurrencyLimiter
+		// parent the instance which the fork is created from,
+		// the concurrency and queue of parent are shared
+		parent *Instance
 	}
 )
 type CustomMocker func(*Config) (*Response, error)
 
+// responseDecoder decodes the response to result,
+// err is the *HTTPError if the status of response is invalid
+type responseDecoder interface {
+	decode(resp *Response, err error) error
+}
+
 var ErrTooManyRequests = errors.New("too many request of the instance")
+var ErrCircuitOpen = errors.New("circuit breaker is open")
 
 func newRequest(config *Config) (req *http.Request, err error) {
 	if config.Method == "" {
@@ -63,6 +79,14 @@ func newRequest(config *Config) (req *http.Request, err error) {
 	if err != nil {
 		return
 	}
+	// 在创建请求之后再包装，保留content length以及get body
+	if config.OnUploadProgress != nil && req.Body != nil && req.Body != http.NoBody {
+		total := req.ContentLength
+		if total == 0 {
+			total = -1
+		}
+		req.Body = newProgressReader(req.Body, total, config.ProgressInterval, config.OnUploadProgress)
+	}
 
 	return
 }
@@ -93,9 +117,18 @@ func mergeConfig(config *Config, insConfig *InstanceConfig) {
 		}
 	}
 
-	if config.Timeout == 0 {
+	if config.Timeout == 0 && !config.noTimeout {
 		config.Timeout = insConfig.Timeout
 	}
+	if config.Retry == nil {
+		config.Retry = insConfig.Retry
+	}
+	if config.Compression == nil {
+		config.Compression = insConfig.Compression
+	}
+	if config.ValidateStatus == nil {
+		config.ValidateStatus = insConfig.ValidateStatus
+	}
 	if config.Client == nil {
 		config.Client = insConfig.Client
 	}
@@ -129,30 +162,104 @@ func NewInstance(config *InstanceConfig) *Instance {
 	if config == nil {
 		config = &InstanceConfig{}
 	}
-	return &Instance{
+	ins := &Instance{
 		Config: config,
 	}
+	if config.CircuitBreaker != nil {
+		ins.circuitBreaker = newCircuitBreaker(config.CircuitBreaker, config.doCircuitStateChange)
+	}
+	if config.RateLimiter != nil {
+		ins.rateLimiter = newRateLimiter(config.RateLimiter)
+	}
+	if config.EnableSession {
+		ins.cookieJar = NewCookieJar()
+	}
+	if config.BandwidthLimit > 0 {
+		ins.bandwidth = newBandwidthBucket(config.BandwidthLimit)
+	}
+	if config.ConcurrencyLimit != nil {
+		ins.concurrencyLimiter = newConcurrencyLimiter(config.ConcurrencyLimit)
+	}
+	return ins
+}
+
+// root returns the instance which holds the concurrency and queue,
+// it's the parent for forked instance
+func (ins *Instance) root() *Instance {
+	if ins.parent != nil {
+		return ins.parent
+	}
+	return ins
 }
 
 func (ins *Instance) request(config *Config) (resp *Response, err error) {
 	// 合并config必须放在第一步，因为有些事件是在instance中生成
 	mergeConfig(config, ins.Config)
 
-	if ins.Config.MaxConcurrency < 0 {
+	// fork的实例与原实例共享并发数
+	root := ins.root()
+	if root.Config.MaxConcurrency < 0 {
 		return nil, ErrRequestIsForbidden
 	}
-	config.Concurrency = atomic.AddUint32(&ins.concurrency, 1)
-	defer atomic.AddUint32(&ins.concurrency, ^uint32(0))
+	var release func()
+	queued := root.Config.QueueSize > 0 && atomic.LoadInt32(&root.Config.MaxConcurrency) > 0
+	// 如果配置了等待队列，则并发数满时排队等待
+	if queued {
+		config.Concurrency, err = root.queue.acquire(root, config)
+		if err != nil {
+			return
+		}
+		release = func() {
+			root.queue.release(root)
+		}
+	} else {
+		config.Concurrency = atomic.AddUint32(&root.concurrency, 1)
+		release = func() {
+			atomic.AddUint32(&root.concurrency, ^uint32(0))
+		}
+	}
+	defer func() {
+		// 流式响应在关闭时才释放
+		if sb := getStreamBody(resp); sb != nil && err == nil {
+			sb.onClose(func(_ error) {
+				release()
+			})
+			return
+		}
+		release()
+	}()
 	// 如果配置了最大请求数，而且当前请求大于最大请求数
-	if ins.Config.MaxConcurrency != 0 && int32(config.Concurrency) > ins.Config.MaxConcurrency {
+	if !queued && root.Config.MaxConcurrency != 0 && int32(config.Concurrency) > root.Config.MaxConcurrency {
 		err = ErrTooManyRequests
 		return
 	}
+	// 按host与route限制并发
+	limitRelease, err := ins.concurrencyLimiter.acquire(config)
+	if err != nil {
+		return
+	}
+	insRelease := release
+	release = func() {
+		limitRelease()
+		insRelease()
+	}
 
 	adapter := config.Adapter
 	if adapter == nil {
 		adapter = defaultAdapter
 	}
+	// 在请求流程中包装adapter(如认证的401重试)，mock或请求的adapter均生效
+	for _, fn := range ins.Config.adapterWrappers {
+		adapter = fn(adapter)
+	}
+	// 启用session则由session adapter处理cookie
+	if ins.cookieJar != nil {
+		adapter = newSessionAdapter(ins.cookieJar, adapter)
+	}
+	// 启用缓存则由缓存adapter处理，启用session时按session区分缓存
+	if ins.Config.Cache != nil {
+		adapter = newCacheAdapter(ins.Config.Cache, ins.cookieJar.cacheSession(), adapter)
+	}
 
 	if config.TransformResponse == nil {
 		config.TransformResponse = DefaultTransformResponse
@@ -172,6 +279,70 @@ func (ins *Instance) request(config *Config) (resp *Response, err error) {
 		return config.Response, nil
 	}
 
+	policy := config.Retry
+	rewind := func() error {
+		return nil
+	}
+	if policy.enabled() {
+		var release func()
+		rewind, release, err = config.newBodyRewinder()
+		if err != nil {
+			return
+		}
+		defer release()
+		// 无法重新读取的body不重试
+		if rewind == nil {
+			policy = nil
+		}
+	}
+	// 熔断的key在请求前生成(创建请求时会以url path作为默认的route)
+	circuitKey := ins.circuitBreaker.key(config)
+	// 每次请求均基于原始的context生成
+	ctx := config.Context
+	for {
+		config.Context = ctx
+		config.Attempts++
+		// 熔断在限流之前判断，熔断的请求不消耗令牌
+		err = ins.circuitBreaker.allow(circuitKey)
+		if err == nil {
+			// 限流需要在创建请求之前处理
+			err = ins.rateLimiter.take(ctx, config)
+			if err != nil {
+				ins.circuitBreaker.cancel(circuitKey)
+				resp = nil
+				break
+			}
+			resp, err = ins.attempt(config, adapter)
+			ins.circuitBreaker.done(circuitKey, config, resp, err)
+		} else {
+			resp = nil
+		}
+		ins.rateLimiter.adapt(config, resp)
+		// 每次请求的超时context在请求完成后已取消，重试判断使用原始的context
+		config.Context = ctx
+		if !policy.shouldRetry(config, resp, err) {
+			break
+		}
+		// 重试前关闭流式响应
+		if sb := getStreamBody(resp); sb != nil {
+			_ = sb.Close()
+		}
+		e := policy.wait(ctx, config.Attempts)
+		if e == nil {
+			e = rewind()
+		}
+		// 如果等待或重置请求数据失败，则返回最后一次请求的结果
+		if e != nil {
+			break
+		}
+	}
+	return
+}
+
+// attempt does the http request once
+func (ins *Instance) attempt(config *Config, adapter Adapter) (resp *Response, err error) {
+	config.Response = nil
+	config.HTTPTrace = nil
 	req, err := newRequest(config)
 	if err != nil {
 		return
@@ -193,7 +364,16 @@ func (ins *Instance) request(config *Config) (resp *Response, err error) {
 			ctx = context.Background()
 		}
 		ctx, cancel := context.WithTimeout(ctx, config.Timeout)
-		defer cancel()
+		defer func() {
+			// 流式响应在关闭时才取消
+			if sb := getStreamBody(resp); sb != nil && err == nil {
+				sb.onClose(func(_ error) {
+					cancel()
+				})
+				return
+			}
+			cancel()
+		}()
 		config.Context = ctx
 	}
 	if config.Context != nil {
@@ -229,6 +409,12 @@ func (ins *Instance) request(config *Config) (resp *Response, err error) {
 		}
 	}
 
+	// 限制带宽(请求数据以及默认adapter的响应数据)
+	config.bandwidthBuckets = ins.bandwidthBuckets(config)
+	if len(config.bandwidthBuckets) != 0 && config.Request.Body != nil {
+		config.Request.Body = newThrottledReader(config.Request.Context(), config.Request.Body, config.bandwidthBuckets)
+	}
+
 	resp, err = adapter(config)
 	if config.HTTPTrace != nil {
 		config.HTTPTrace.Finish()
@@ -239,15 +425,35 @@ func (ins *Instance) request(config *Config) (resp *Response, err error) {
 	}
 	resp.Config = config
 	resp.Request = config.Request
-	data := resp.Data
-	// 响应数据的相关转换
-	for _, fn := range config.TransformResponse {
-		data, err = fn(data, resp.Headers)
-		if err != nil {
+	if config.Stream && resp.Body != nil {
+		// 流式响应直接解码body，不执行transform
+		sb, e := newStreamBody(resp.Body, resp.Headers)
+		if e != nil {
+			err = e
 			return
 		}
+		resp.Body = sb
+		defer func() {
+			if err != nil {
+				_ = sb.Close()
+			}
+		}()
+	} else {
+		data := resp.Data
+		// 保留转换前的数据与响应头，用于校验content digest等
+		resp.rawData = data
+		if resp.Headers.Get(headerContentEncoding) != "" {
+			resp.rawHeaders = resp.Headers.Clone()
+		}
+		// 响应数据的相关转换
+		for _, fn := range config.TransformResponse {
+			data, err = fn(data, resp.Headers)
+			if err != nil {
+				return
+			}
+		}
+		resp.Data = data
 	}
-	resp.Data = data
 
 	// 响应完成后的相关响应拦截器
 	for _, fn := range config.ResponseInterceptors {
@@ -256,6 +462,8 @@ func (ins *Instance) request(config *Config) (resp *Response, err error) {
 			return
 		}
 	}
+	// 拦截器未转换为出错时，校验响应状态码
+	err = config.validateStatus(resp)
 
 	return
 }
@@ -263,16 +471,36 @@ func (ins *Instance) request(config *Config) (resp *Response, err error) {
 // doRequest do http request
 func (ins *Instance) doRequest(config *Config, result interface{}) (resp *Response, err error) {
 	resp, err = ins.request(config)
-	if err != nil {
+	decoder, _ := result.(responseDecoder)
+	// 状态码校验失败的响应由decoder转换后再触发error listener(如typed的response error)
+	he := &HTTPError{}
+	decodeHTTPError := decoder != nil && resp != nil && errors.As(err, &he)
+	if err != nil && !decodeHTTPError {
 		newErr := config.doError(err)
 		if newErr != nil {
 			err = newErr
 		}
 	}
-	// 如果没有出错，有响应结果，而且指定了result(需要unmarshal)
-	if err == nil &&
+	// 流式响应在关闭时才触发done事件
+	if sb := getStreamBody(resp); sb != nil && err == nil {
+		sb.onClose(func(readErr error) {
+			config.doDone(resp, readErr)
+		})
+		return
+	}
+	if decoder != nil && resp != nil && (err == nil || decodeHTTPError) {
+		err = decoder.decode(resp, err)
+		// decoder的出错也经过error listener
+		if err != nil {
+			newErr := config.doError(err)
+			if newErr != nil {
+				err = newErr
+			}
+		}
+	} else if err == nil &&
 		resp != nil &&
 		result != nil {
+		// 如果没有出错，有响应结果，而且指定了result(需要unmarshal)
 		err = resp.JSON(result)
 	}
 	config.doDone(resp, err)
@@ -281,12 +509,44 @@ func (ins *Instance) doRequest(config *Config, result interface{}) (resp *Respon
 
 // GetConcurrency get concurrency of instance
 func (ins *Instance) GetConcurrency() uint32 {
-	return atomic.LoadUint32(&ins.concurrency)
+	return atomic.LoadUint32(&ins.root().concurrency)
+}
+
+// GetHostConcurrency get concurrency of each host,
+// it's empty if concurrency limit is not configured
+func (ins *Instance) GetHostConcurrency() map[string]uint32 {
+	return ins.concurrencyLimiter.hostCounts()
+}
+
+// GetRouteConcurrency get concurrency of each route which has concurrency limit
+func (ins *Instance) GetRouteConcurrency() map[string]uint32 {
+	return ins.concurrencyLimiter.routeCounts()
+}
+
+// GetQueueLength get the count of requests waiting in the queue
+func (ins *Instance) GetQueueLength() int {
+	return ins.root().queue.length()
+}
+
+// GetRateLimitTokens get the available tokens of route's rate limiter,
+// the global rate limiter is used if route is empty,
+// it returns false if the rate limiter is not configured
+func (ins *Instance) GetRateLimitTokens(route string) (float64, bool) {
+	return ins.rateLimiter.tokens(route)
+}
+
+// GetCircuitState get circuit state of the key,
+// it returns CircuitClosed if circuit breaker is not enabled
+func (ins *Instance) GetCircuitState(key string) CircuitState {
+	if ins.circuitBreaker == nil {
+		return CircuitClosed
+	}
+	return ins.circuitBreaker.state(key)
 }
 
 // SetMaxConcurrency sets max concurrency for instance
 func (ins *Instance) SetMaxConcurrency(value int32) {
-	atomic.StoreInt32(&ins.Config.MaxConcurrency, value)
+	atomic.StoreInt32(&ins.root().Config.MaxConcurrency, value)
 }
 
 // Request http request
@@ -559,8 +819,8 @@ func (ins *Instance) EnhancePatch(result interface{}, url string, data interface
 }
 
 // uploadX uploads file
-func (ins *Instance) uploadX(context context.Context, result interface{}, url string, file *multipartFile, query ...url.Values) (resp *Response, err error) {
-	data, err := file.Bytes()
+func (ins *Instance) uploadX(context context.Context, result interface{}, url string, file MultipartBody, query ...url.Values) (resp *Response, err error) {
+	data, err := multipartBodyData(file)
 	if err != nil {
 		return nil, err
 	}
@@ -582,17 +842,17 @@ func (ins *Instance) uploadX(context context.Context, result interface{}, url st
 }
 
 // UploadX uploads file with context
-func (ins *Instance) UploadX(context context.Context, url string, file *multipartFile, query ...url.Values) (resp *Response, err error) {
+func (ins *Instance) UploadX(context context.Context, url string, file MultipartBody, query ...url.Values) (resp *Response, err error) {
 	return ins.uploadX(context, nil, url, file, query...)
 }
 
 // Upload uploads file
-func (ins *Instance) Upload(url string, file *multipartFile, query ...url.Values) (resp *Response, err error) {
+func (ins *Instance) Upload(url string, file MultipartBody, query ...url.Values) (resp *Response, err error) {
 	return ins.uploadX(context.Background(), nil, url, file, query...)
 }
 
 // EnhanceUploadX uploads file with context and convert response to result
-func (ins *Instance) EnhanceUploadX(context context.Context, result interface{}, url string, file *multipartFile, query ...url.Values) (err error) {
+func (ins *Instance) EnhanceUploadX(context context.Context, result interface{}, url string, file MultipartBody, query ...url.Values) (err error) {
 	_, err = ins.uploadX(context, result, url, file, query...)
 	if err != nil {
 		return err
@@ -601,7 +861,7 @@ func (ins *Instance) EnhanceUploadX(context context.Context, result interface{},
 }
 
 // EnhanceUpload uploads file and convert response to result
-func (ins *Instance) EnhanceUpload(result interface{}, url string, file *multipartFile, query ...url.Values) (err error) {
+func (ins *Instance) EnhanceUpload(result interface{}, url string, file MultipartBody, query ...url.Values) (err error) {
 	_, err = ins.uploadX(context.Background(), result, url, file, query...)
 	if err != nil {
 		return err
@@ -845,6 +845,29 @@ func TestRequestForbidden(t *testing.T) {
 	assert.Nil(err)
 }
 
+func TestEnhanceRequestUnmarshalError(t *testing.T) {
+	assert := assert.New(t)
+
+	onErrorCount := 0
+	ins := NewInstance(&InstanceConfig{
+		OnError: func(err error, config *Config) error {
+			onErrorCount++
+			return nil
+		},
+		Adapter: func(config *Config) (*Response, error) {
+			return &Response{
+				Status: 200,
+				Data:   []byte("not json"),
+			}, nil
+		},
+	})
+	result := make(map[string]string)
+	err := ins.EnhanceGet(&result, "/")
+	assert.NotNil(err)
+	// 响应数据的解析出错不触发error listener
+	assert.Equal(0, onErrorCount)
+}
+
 func TestMock(t *testing.T) {
 	assert := assert.New(t)
 	ins := NewInstance(nil)
@@ -0,0 +1,613 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureAlgHMACSHA256 hmac using sha-256
+	SignatureAlgHMACSHA256 = "hmac-sha256"
+	// SignatureAlgEd25519 edwards-curve digital signature algorithm using curve edwards25519
+	SignatureAlgEd25519 = "ed25519"
+	// SignatureAlgECDSAP256SHA256 ecdsa using curve p-256 and sha-256
+	SignatureAlgECDSAP256SHA256 = "ecdsa-p256-sha256"
+	// SignatureAlgECDSAP384SHA384 ecdsa using curve p-384 and sha-384
+	SignatureAlgECDSAP384SHA384 = "ecdsa-p384-sha384"
+
+	headerSignature      = "Signature"
+	headerSignatureInput = "Signature-Input"
+
+	defaultSignatureLabel = "sig1"
+)
+
+type (
+	// SignatureKey key of http message signature, the key is []byte for hmac,
+	// ed25519.PrivateKey/ed25519.PublicKey for ed25519,
+	// *ecdsa.PrivateKey/*ecdsa.PublicKey for ecdsa
+	SignatureKey struct {
+		// ID the key id
+		ID string
+		// Algorithm the algorithm of signature
+		Algorithm string
+		// Key the key of algorithm
+		Key interface{}
+	}
+
+	// MessageSignerConfig config of http message signer
+	MessageSignerConfig struct {
+		// Key the key to sign request
+		Key *SignatureKey
+		// Label the label of signature, default is sig1
+		Label string
+		// Components covered components, default is @method, @authority,
+		// @path, @query and content-digest(if ContentDigest is set)
+		Components []string
+		// ContentDigest the algorithm of Content-Digest header, it's not set if empty
+		ContentDigest string
+		// Expires the expires of signature, no expires if it's 0
+		Expires time.Duration
+		// Tag the tag of signature
+		Tag string
+	}
+
+	// MessageSigner signs the request as rfc 9421
+	MessageSigner struct {
+		config MessageSignerConfig
+		now    func() time.Time
+	}
+
+	// MessageVerifierConfig config of http message verifier
+	MessageVerifierConfig struct {
+		// Keys returns the key of key id
+		Keys func(keyID string) (*SignatureKey, error)
+		// Label the label of signature to verify, the first one is used if empty
+		Label string
+		// RequiredComponents the components must be covered by signature
+		RequiredComponents []string
+		// RequireContentDigest the Content-Digest header is required,
+		// and it must be covered by signature
+		RequireContentDigest bool
+		// MaxAge max age of signature from created, no limit if it's 0
+		MaxAge time.Duration
+	}
+
+	// MessageVerifier verifies the signature and content digest of response
+	MessageVerifier struct {
+		config MessageVerifierConfig
+		now    func() time.Time
+	}
+
+	// SignatureError error of http message signature
+	SignatureError struct {
+		// Label the label of signature
+		Label string
+		// Reason the reason of error
+		Reason string
+		// Err the original error
+		Err error
+	}
+
+	signatureParams struct {
+		components []string
+		created    int64
+		expires    int64
+		keyID      string
+		alg        string
+		tag        string
+		raw        string
+	}
+
+	signatureMember struct {
+		label string
+		value string
+	}
+)
+
+func (e *SignatureError) Error() string {
+	msg := "signature verification fail"
+	if e.Label != "" {
+		msg += ", label: " + e.Label
+	}
+	msg += ", reason: " + e.Reason
+	if e.Err != nil {
+		msg += ", error: " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// signatureComponentValue returns the value of component,
+// the response only supports @status and headers
+func signatureComponentValue(name string, req *http.Request, resp *Response) (string, error) {
+	if !strings.HasPrefix(name, "@") {
+		header := http.Header{}
+		if resp != nil {
+			header = resp.contentHeaders()
+		} else if req != nil {
+			header = req.Header
+		}
+		values := header.Values(name)
+		if len(values) == 0 {
+			return "", fmt.Errorf("header %s is missing", name)
+		}
+		items := make([]string, len(values))
+		for i, value := range values {
+			items[i] = strings.TrimSpace(value)
+		}
+		return strings.Join(items, ", "), nil
+	}
+	if resp != nil {
+		if name == "@status" {
+			return strconv.Itoa(resp.Status), nil
+		}
+		return "", fmt.Errorf("component %s is not supported for response", name)
+	}
+	u := req.URL
+	switch name {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		return u.String(), nil
+	case "@authority":
+		return strings.ToLower(getRequestHost(req)), nil
+	case "@scheme":
+		return strings.ToLower(u.Scheme), nil
+	case "@request-target":
+		return u.RequestURI(), nil
+	case "@path":
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		return path, nil
+	case "@query":
+		return "?" + u.RawQuery, nil
+	}
+	return "", fmt.Errorf("component %s is not supported for request", name)
+}
+
+// serialize serializes the signature params
+func (p *signatureParams) serialize() string {
+	items := make([]string, len(p.components))
+	for i, name := range p.components {
+		items[i] = strconv.Quote(name)
+	}
+	result := "(" + strings.Join(items, " ") + ")"
+	if p.created != 0 {
+		result += ";created=" + strconv.FormatInt(p.created, 10)
+	}
+	if p.expires != 0 {
+		result += ";expires=" + strconv.FormatInt(p.expires, 10)
+	}
+	if p.keyID != "" {
+		result += ";keyid=" + strconv.Quote(p.keyID)
+	}
+	if p.alg != "" {
+		result += ";alg=" + strconv.Quote(p.alg)
+	}
+	if p.tag != "" {
+		result += ";tag=" + strconv.Quote(p.tag)
+	}
+	return result
+}
+
+// signatureBase creates the signature base of components
+func signatureBase(params *signatureParams, req *http.Request, resp *Response) (string, error) {
+	builder := strings.Builder{}
+	exists := make(map[string]bool)
+	for _, name := range params.components {
+		if exists[name] {
+			return "", fmt.Errorf("component %s is duplicated", name)
+		}
+		exists[name] = true
+		value, err := signatureComponentValue(name, req, resp)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(strconv.Quote(name) + ": " + value + "\n")
+	}
+	builder.WriteString(`"@signature-params": ` + params.raw)
+	return builder.String(), nil
+}
+
+// splitStructuredField splits the value by sep which is not in quotes or parentheses
+func splitStructuredField(value string, sep byte) []string {
+	result := make([]string, 0)
+	quoted := false
+	depth := 0
+	start := 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '(':
+			depth++
+		case !quoted && c == ')':
+			depth--
+		case !quoted && depth == 0 && c == sep:
+			result = append(result, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(result, strings.TrimSpace(value[start:]))
+}
+
+// parseSignatureDictionary parses the dictionary of Signature and Signature-Input headers
+func parseSignatureDictionary(header http.Header, name string) []signatureMember {
+	members := make([]signatureMember, 0)
+	for _, item := range splitStructuredField(strings.Join(header.Values(name), ","), ',') {
+		label, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		members = append(members, signatureMember{
+			label: strings.TrimSpace(label),
+			value: strings.TrimSpace(value),
+		})
+	}
+	return members
+}
+
+func unquoteSignatureString(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", fmt.Errorf("%s is not a string", value)
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1]), nil
+}
+
+// parseSignatureParams parses the value of Signature-Input
+func parseSignatureParams(value string) (*signatureParams, error) {
+	end := strings.LastIndex(value, ")")
+	if !strings.HasPrefix(value, "(") || end < 0 {
+		return nil, errors.New("signature input is invalid")
+	}
+	params := &signatureParams{
+		raw: value,
+	}
+	for _, item := range splitStructuredField(value[1:end], ' ') {
+		if item == "" {
+			continue
+		}
+		name, err := unquoteSignatureString(item)
+		if err != nil {
+			return nil, err
+		}
+		params.components = append(params.components, name)
+	}
+	for _, item := range splitStructuredField(value[end+1:], ';') {
+		key, v, _ := strings.Cut(item, "=")
+		var err error
+		switch key {
+		case "created":
+			params.created, err = strconv.ParseInt(v, 10, 64)
+		case "expires":
+			params.expires, err = strconv.ParseInt(v, 10, 64)
+		case "keyid":
+			params.keyID, err = unquoteSignatureString(v)
+		case "alg":
+			params.alg, err = unquoteSignatureString(v)
+		case "tag":
+			params.tag, err = unquoteSignatureString(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// ecdsaSize returns the byte size of r and s
+func ecdsaSize(alg string) int {
+	if alg == SignatureAlgECDSAP384SHA384 {
+		return 48
+	}
+	return 32
+}
+
+func ecdsaDigest(alg string, data []byte) []byte {
+	if alg == SignatureAlgECDSAP384SHA384 {
+		sum := sha512.Sum384(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// Sign signs the data
+func (k *SignatureKey) Sign(data []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case SignatureAlgHMACSHA256:
+		secret, ok := k.Key.([]byte)
+		if ok {
+			h := hmac.New(sha256.New, secret)
+			_, _ = h.Write(data)
+			return h.Sum(nil), nil
+		}
+	case SignatureAlgEd25519:
+		key, ok := k.Key.(ed25519.PrivateKey)
+		if ok {
+			return ed25519.Sign(key, data), nil
+		}
+	case SignatureAlgECDSAP256SHA256, SignatureAlgECDSAP384SHA384:
+		key, ok := k.Key.(*ecdsa.PrivateKey)
+		if ok {
+			r, s, err := ecdsa.Sign(rand.Reader, key, ecdsaDigest(k.Algorithm, data))
+			if err != nil {
+				return nil, err
+			}
+			// 签名为r与s固定长度的拼接
+			size := ecdsaSize(k.Algorithm)
+			signature := make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+			return signature, nil
+		}
+	default:
+		return nil, fmt.Errorf("algorithm %s is not supported", k.Algorithm)
+	}
+	return nil, fmt.Errorf("key type %T is invalid for %s", k.Key, k.Algorithm)
+}
+
+// Verify verifies the signature of data
+func (k *SignatureKey) Verify(data, signature []byte) (bool, error) {
+	switch k.Algorithm {
+	case SignatureAlgHMACSHA256:
+		expected, err := k.Sign(data)
+		if err != nil {
+			return false, err
+		}
+		return hmac.Equal(expected, signature), nil
+	case SignatureAlgEd25519:
+		var key ed25519.PublicKey
+		switch v := k.Key.(type) {
+		case ed25519.PublicKey:
+			key = v
+		case ed25519.PrivateKey:
+			key, _ = v.Public().(ed25519.PublicKey)
+		default:
+			return false, fmt.Errorf("key type %T is invalid for %s", k.Key, k.Algorithm)
+		}
+		return ed25519.Verify(key, data, signature), nil
+	case SignatureAlgECDSAP256SHA256, SignatureAlgECDSAP384SHA384:
+		var key *ecdsa.PublicKey
+		switch v := k.Key.(type) {
+		case *ecdsa.PublicKey:
+			key = v
+		case *ecdsa.PrivateKey:
+			key = &v.PublicKey
+		default:
+			return false, fmt.Errorf("key type %T is invalid for %s", k.Key, k.Algorithm)
+		}
+		size := ecdsaSize(k.Algorithm)
+		if len(signature) != 2*size {
+			return false, nil
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, ecdsaDigest(k.Algorithm, data), r, s), nil
+	}
+	return false, fmt.Errorf("algorithm %s is not supported", k.Algorithm)
+}
+
+// NewMessageSigner creates a http message signer
+func NewMessageSigner(config MessageSignerConfig) *MessageSigner {
+	if config.Label == "" {
+		config.Label = defaultSignatureLabel
+	}
+	if len(config.Components) == 0 {
+		config.Components = []string{
+			"@method",
+			"@authority",
+			"@path",
+			"@query",
+		}
+		if config.ContentDigest != "" {
+			config.Components = append(config.Components, "content-digest")
+		}
+	}
+	return &MessageSigner{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// RequestInterceptor sets the Content-Digest header(if enabled), and signs the request
+func (s *MessageSigner) RequestInterceptor(config *Config) error {
+	req := config.Request
+	if s.config.ContentDigest != "" {
+		err := setContentDigest(req, s.config.ContentDigest)
+		if err != nil {
+			return err
+		}
+	}
+	created := s.now().Unix()
+	params := &signatureParams{
+		components: s.config.Components,
+		created:    created,
+		keyID:      s.config.Key.ID,
+		alg:        s.config.Key.Algorithm,
+		tag:        s.config.Tag,
+	}
+	if s.config.Expires != 0 {
+		params.expires = created + int64(s.config.Expires/time.Second)
+	}
+	params.raw = params.serialize()
+	base, err := signatureBase(params, req, nil)
+	if err != nil {
+		return err
+	}
+	signature, err := s.config.Key.Sign([]byte(base))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerSignatureInput, s.config.Label+"="+params.raw)
+	req.Header.Set(headerSignature, s.config.Label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+	return nil
+}
+
+// Attach appends the request interceptor of signer to instance config
+func (s *MessageSigner) Attach(conf *InstanceConfig) {
+	conf.RequestInterceptors = append(conf.RequestInterceptors, s.RequestInterceptor)
+}
+
+// NewMessageVerifier creates a http message verifier
+func NewMessageVerifier(config MessageVerifierConfig) *MessageVerifier {
+	return &MessageVerifier{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// verifyContentDigest verifies the Content-Digest of response with the data
+// before decompression, the stream response can't be verified, so it fails
+// with ErrContentDigestStream if content digest is required
+func (v *MessageVerifier) verifyContentDigest(resp *Response) error {
+	if resp.Body != nil {
+		if v.config.RequireContentDigest {
+			return ErrContentDigestStream
+		}
+		return nil
+	}
+	if resp.Headers.Get(headerContentDigest) == "" {
+		if v.config.RequireContentDigest {
+			return ErrContentDigestMissing
+		}
+		return nil
+	}
+	return VerifyContentDigest(resp.Headers, resp.contentData())
+}
+
+// ResponseInterceptor verifies the content digest and signature of response,
+// it fails with *SignatureError
+func (v *MessageVerifier) ResponseInterceptor(resp *Response) error {
+	err := v.verifyContentDigest(resp)
+	if err != nil {
+		return &SignatureError{
+			Reason: "content digest is invalid",
+			Err:    err,
+		}
+	}
+
+	var input *signatureMember
+	for _, member := range parseSignatureDictionary(resp.Headers, headerSignatureInput) {
+		if v.config.Label == "" || member.label == v.config.Label {
+			m := member
+			input = &m
+			break
+		}
+	}
+	if input == nil {
+		return &SignatureError{
+			Label:  v.config.Label,
+			Reason: "signature is missing",
+		}
+	}
+	label := input.label
+	newError := func(reason string, err error) error {
+		return &SignatureError{
+			Label:  label,
+			Reason: reason,
+			Err:    err,
+		}
+	}
+	var signature []byte
+	for _, member := range parseSignatureDictionary(resp.Headers, headerSignature) {
+		if member.label == label {
+			signature, err = base64.StdEncoding.DecodeString(strings.Trim(member.value, ":"))
+			if err != nil {
+				return newError("signature is invalid", err)
+			}
+		}
+	}
+	if len(signature) == 0 {
+		return newError("signature is missing", nil)
+	}
+	params, err := parseSignatureParams(input.value)
+	if err != nil {
+		return newError("signature input is invalid", err)
+	}
+
+	covered := make(map[string]bool)
+	for _, name := range params.components {
+		covered[name] = true
+	}
+	required := v.config.RequiredComponents
+	if v.config.RequireContentDigest {
+		required = append([]string{
+			"content-digest",
+		}, required...)
+	}
+	for _, name := range required {
+		if !covered[name] {
+			return newError(fmt.Sprintf("component %s is not covered", name), nil)
+		}
+	}
+	now := v.now().Unix()
+	if params.expires != 0 && now > params.expires {
+		return newError("signature is expired", nil)
+	}
+	if v.config.MaxAge != 0 && now-params.created > int64(v.config.MaxAge/time.Second) {
+		return newError("signature is too old", nil)
+	}
+
+	if v.config.Keys == nil {
+		return newError("key is not found", nil)
+	}
+	key, err := v.config.Keys(params.keyID)
+	if err != nil || key == nil {
+		return newError("key is not found", err)
+	}
+	if params.alg != "" && params.alg != key.Algorithm {
+		return newError("algorithm is mismatch", nil)
+	}
+	base, err := signatureBase(params, nil, resp)
+	if err != nil {
+		return newError("signature base is invalid", err)
+	}
+	ok, err := key.Verify([]byte(base), signature)
+	if err != nil {
+		return newError("signature is invalid", err)
+	}
+	if !ok {
+		return newError("signature is mismatch", nil)
+	}
+	return nil
+}
+
+// Attach appends the response interceptor of verifier to instance config
+func (v *MessageVerifier) Attach(conf *InstanceConfig) {
+	conf.ResponseInterceptors = append(conf.ResponseInterceptors, v.ResponseInterceptor)
+}
@@ -0,0 +1,280 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureBase(t *testing.T) {
+	assert := assert.New(t)
+
+	// rfc 9421 B.2.5
+	secret, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	req, _ := http.NewRequest("POST", "https://example.com/foo?param=Value&Pet=dog", nil)
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set(headerContentType, "application/json")
+	params := &signatureParams{
+		components: []string{
+			"date",
+			"@authority",
+			"content-type",
+		},
+		created: 1618884473,
+		keyID:   "test-shared-secret",
+	}
+	params.raw = params.serialize()
+	base, err := signatureBase(params, req, nil)
+	assert.Nil(err)
+	assert.Equal(`"date": Tue, 20 Apr 2021 02:07:55 GMT
+"@authority": example.com
+"content-type": application/json
+"@signature-params": ("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`, base)
+	key := &SignatureKey{
+		Algorithm: SignatureAlgHMACSHA256,
+		Key:       secret,
+	}
+	signature, err := key.Sign([]byte(base))
+	assert.Nil(err)
+	assert.Equal("pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=", base64.StdEncoding.EncodeToString(signature))
+
+	result, err := parseSignatureParams(params.raw)
+	assert.Nil(err)
+	assert.Equal(params, result)
+
+	for _, name := range []string{
+		"@method",
+		"@target-uri",
+		"@scheme",
+		"@request-target",
+		"@path",
+		"@query",
+	} {
+		value, err := signatureComponentValue(name, req, nil)
+		assert.Nil(err)
+		assert.Equal(map[string]string{
+			"@method":         "POST",
+			"@target-uri":     "https://example.com/foo?param=Value&Pet=dog",
+			"@scheme":         "https",
+			"@request-target": "/foo?param=Value&Pet=dog",
+			"@path":           "/foo",
+			"@query":          "?param=Value&Pet=dog",
+		}[name], value)
+	}
+	_, err = signatureComponentValue("@method", nil, &Response{})
+	assert.NotNil(err)
+	_, err = signatureComponentValue("x-none", req, nil)
+	assert.NotNil(err)
+}
+
+func TestSignatureKey(t *testing.T) {
+	assert := assert.New(t)
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	data := []byte("abc")
+	for _, key := range []*SignatureKey{
+		{
+			Algorithm: SignatureAlgHMACSHA256,
+			Key:       []byte("secret"),
+		},
+		{
+			Algorithm: SignatureAlgEd25519,
+			Key:       edKey,
+		},
+		{
+			Algorithm: SignatureAlgECDSAP256SHA256,
+			Key:       p256Key,
+		},
+		{
+			Algorithm: SignatureAlgECDSAP384SHA384,
+			Key:       p384Key,
+		},
+	} {
+		signature, err := key.Sign(data)
+		assert.Nil(err)
+		ok, err := key.Verify(data, signature)
+		assert.Nil(err)
+		assert.True(ok, key.Algorithm)
+		ok, _ = key.Verify([]byte("abcd"), signature)
+		assert.False(ok, key.Algorithm)
+	}
+
+	_, err := (&SignatureKey{
+		Algorithm: SignatureAlgEd25519,
+		Key:       []byte("secret"),
+	}).Sign(data)
+	assert.NotNil(err)
+	_, err = (&SignatureKey{
+		Algorithm: "rsa-v1_5-sha256",
+	}).Sign(data)
+	assert.NotNil(err)
+}
+
+func TestMessageSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	requestKey := &SignatureKey{
+		ID:        "client",
+		Algorithm: SignatureAlgEd25519,
+		Key:       edKey,
+	}
+	responseKey := &SignatureKey{
+		ID:        "server",
+		Algorithm: SignatureAlgHMACSHA256,
+		Key:       []byte("secret"),
+	}
+	signer := NewMessageSigner(MessageSignerConfig{
+		Key:           requestKey,
+		ContentDigest: DigestSHA256,
+		Expires:       time.Minute,
+	})
+	verifier := NewMessageVerifier(MessageVerifierConfig{
+		Keys: func(keyID string) (*SignatureKey, error) {
+			if keyID != responseKey.ID {
+				return nil, errors.New("unknown key")
+			}
+			return responseKey, nil
+		},
+		RequiredComponents: []string{
+			"@status",
+		},
+		RequireContentDigest: true,
+		MaxAge:               time.Minute,
+	})
+
+	conf := &InstanceConfig{
+		BaseURL: "https://example.com",
+		Adapter: func(config *Config) (*Response, error) {
+			req := config.Request
+			// 校验请求的签名
+			params, err := parseSignatureParams(strings.TrimPrefix(req.Header.Get(headerSignatureInput), "sig1="))
+			if err != nil {
+				return nil, err
+			}
+			base, err := signatureBase(params, req, nil)
+			if err != nil {
+				return nil, err
+			}
+			signature, _ := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimPrefix(req.Header.Get(headerSignature), "sig1="), ":"))
+			ok, _ := requestKey.Verify([]byte(base), signature)
+			if !ok {
+				return nil, errors.New("request signature is invalid")
+			}
+
+			data := []byte(`{"name":"tree"}`)
+			resp := &Response{
+				Status:  200,
+				Headers: http.Header{},
+			}
+			components := []string{
+				"@status",
+				"content-digest",
+			}
+			// content digest基于压缩后的数据
+			if config.Route == "/gzip" {
+				buf := new(bytes.Buffer)
+				w := gzip.NewWriter(buf)
+				_, _ = w.Write(data)
+				_ = w.Close()
+				data = buf.Bytes()
+				resp.Headers.Set(headerContentEncoding, gzipEncoding)
+				components = append(components, "content-encoding")
+			}
+			resp.Data = data
+			digest, _ := ContentDigest(DigestSHA256, bytes.NewReader(data))
+			resp.Headers.Set(headerContentDigest, digest)
+			params = &signatureParams{
+				components: components,
+				created:    time.Now().Unix(),
+				keyID:      responseKey.ID,
+			}
+			if config.Route == "/expired" {
+				params.created -= 3600
+			}
+			params.raw = params.serialize()
+			base, _ = signatureBase(params, nil, resp)
+			signature, _ = responseKey.Sign([]byte(base))
+			resp.Headers.Set(headerSignatureInput, "sig1="+params.raw)
+			resp.Headers.Set(headerSignature, "sig1=:"+base64.StdEncoding.EncodeToString(signature)+":")
+			if config.Route == "/tampered" {
+				resp.Data = []byte(`{"name":"xie"}`)
+			}
+			if config.Stream {
+				resp.Body = io.NopCloser(bytes.NewReader(resp.Data))
+				resp.Data = nil
+			}
+			return resp, nil
+		},
+	}
+	signer.Attach(conf)
+	verifier.Attach(conf)
+	ins := NewInstance(conf)
+
+	resp, err := ins.Post("/users", map[string]string{
+		"name": "tree",
+	})
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	input := resp.Request.Header.Get(headerSignatureInput)
+	assert.True(strings.HasPrefix(input, `sig1=("@method" "@authority" "@path" "@query" "content-digest");created=`))
+	assert.Contains(input, `;keyid="client";alg="ed25519"`)
+
+	resp, err = ins.Get("/gzip")
+	assert.Nil(err)
+	assert.Equal(`{"name":"tree"}`, string(resp.Data))
+	assert.Empty(resp.Headers.Get(headerContentEncoding))
+
+	_, err = ins.Get("/tampered")
+	se := &SignatureError{}
+	assert.True(errors.As(err, &se))
+	assert.Equal("content digest is invalid", se.Reason)
+	assert.True(errors.Is(err, ErrContentDigestMismatch))
+
+	_, err = ins.Get("/expired")
+	assert.True(errors.As(err, &se))
+	assert.Equal("signature is too old", se.Reason)
+	assert.Equal("signature verification fail, label: sig1, reason: signature is too old", se.Error())
+
+	// stream的响应无法校验content digest
+	_, err = ins.Request(&Config{
+		URL:    "/stream",
+		Stream: true,
+	})
+	assert.True(errors.As(err, &se))
+	assert.True(errors.Is(err, ErrContentDigestStream))
+	assert.Nil(NewMessageVerifier(MessageVerifierConfig{}).verifyContentDigest(&Response{
+		Headers: http.Header{
+			headerContentDigest: []string{"sha-256=:abc:"},
+		},
+		Body: io.NopCloser(strings.NewReader("abc")),
+	}))
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// metricsStartedAt the key of request start time in config
+	metricsStartedAt = "_metricsStartedAt"
+	// ErrCategoryOther the category of error which is not net error
+	ErrCategoryOther = "other"
+
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultMetricsBuckets default buckets(seconds) of latency histogram
+var DefaultMetricsBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+type (
+	// MetricsConfig config of metrics collector
+	MetricsConfig struct {
+		// Namespace the prefix of metric name, default is axios
+		Namespace string
+		// Buckets buckets(seconds) of latency histogram, default is DefaultMetricsBuckets
+		Buckets []float64
+	}
+
+	// MetricsCollector collects the metrics of requests,
+	// and exposes them in prometheus text format
+	MetricsCollector struct {
+		mutex     sync.Mutex
+		namespace string
+		buckets   []float64
+		requests  map[metricsLabels]uint64
+		durations map[metricsLabels]*metricsHistogram
+		phases    map[metricsPhaseLabels]*metricsHistogram
+	}
+
+	metricsLabels struct {
+		method   string
+		route    string
+		status   string
+		category string
+	}
+
+	metricsPhaseLabels struct {
+		method string
+		route  string
+		phase  string
+	}
+
+	// metricsKey labels of metric
+	metricsKey interface {
+		comparable
+		fmt.Stringer
+	}
+
+	metricsHistogram struct {
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+)
+
+// NewMetricsCollector creates a metrics collector
+func NewMetricsCollector(config MetricsConfig) *MetricsCollector {
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = "axios"
+	}
+	buckets := config.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &MetricsCollector{
+		namespace: namespace,
+		buckets:   buckets,
+		requests:  make(map[metricsLabels]uint64),
+		durations: make(map[metricsLabels]*metricsHistogram),
+		phases:    make(map[metricsPhaseLabels]*metricsHistogram),
+	}
+}
+
+// Register adds the listeners of collector to instance
+func (m *MetricsCollector) Register(ins *Instance) {
+	ins.Config.AddBeforeNewRequestListener(m.OnBeforeNewRequest)
+	ins.Config.AddDoneListener(m.OnDone)
+}
+
+// OnBeforeNewRequest records the start time of request,
+// the latency includes all attempts of request
+func (m *MetricsCollector) OnBeforeNewRequest(config *Config) error {
+	config.Set(metricsStartedAt, time.Now())
+	return nil
+}
+
+// OnDone collects the metrics of request
+func (m *MetricsCollector) OnDone(config *Config, resp *Response, err error) {
+	status := -1
+	if resp != nil {
+		status = resp.Status
+	}
+	category := ""
+	if err != nil {
+		category = GetInternalErrorCategory(err)
+		if category == "" {
+			category = ErrCategoryOther
+		}
+	}
+	labels := metricsLabels{
+		method:   config.Method,
+		route:    config.getRoute(),
+		status:   strconv.Itoa(status),
+		category: category,
+	}
+	var use time.Duration
+	if startedAt, ok := config.Get(metricsStartedAt).(time.Time); ok {
+		use = time.Since(startedAt)
+	} else if config.HTTPTrace != nil {
+		use = config.HTTPTrace.Stats().Total
+	}
+	phases := make(map[string]time.Duration)
+	if config.HTTPTrace != nil {
+		timelineStats := config.HTTPTrace.Stats()
+		phases["dns"] = timelineStats.DNSLookup
+		phases["tcp"] = timelineStats.TCPConnection
+		phases["tls"] = timelineStats.TLSHandshake
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests[labels]++
+	observeHistogram(m, m.durations, labels, use)
+	for phase, d := range phases {
+		// 复用的连接无此阶段
+		if d <= 0 {
+			continue
+		}
+		observeHistogram(m, m.phases, metricsPhaseLabels{
+			method: labels.method,
+			route:  labels.route,
+			phase:  phase,
+		}, d)
+	}
+}
+
+func observeHistogram[K comparable](m *MetricsCollector, histograms map[K]*metricsHistogram, key K, d time.Duration) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &metricsHistogram{
+			counts: make([]uint64, len(m.buckets)),
+		}
+		histograms[key] = h
+	}
+	h.observe(m.buckets, d.Seconds())
+}
+
+func (h *metricsHistogram) observe(buckets []float64, value float64) {
+	for i, bucket := range buckets {
+		if value <= bucket {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+var metricsLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatMetricsLabels formats the labels, the pairs are name and value
+func formatMetricsLabels(pairs ...string) string {
+	items := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		items = append(items, fmt.Sprintf(`%s="%s"`, pairs[i], metricsLabelReplacer.Replace(pairs[i+1])))
+	}
+	return strings.Join(items, ",")
+}
+
+func formatMetricsFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func (l metricsLabels) String() string {
+	return formatMetricsLabels("method", l.method, "route", l.route, "status", l.status, "category", l.category)
+}
+
+func (l metricsPhaseLabels) String() string {
+	return formatMetricsLabels("method", l.method, "route", l.route, "phase", l.phase)
+}
+
+func (m *MetricsCollector) writeHistogram(w io.Writer, name string, labels string, h *metricsHistogram) {
+	for i, bucket := range m.buckets {
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatMetricsFloat(bucket), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatMetricsFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+// sortedKeys returns the keys sorted by the string of labels
+func sortedKeys[K metricsKey, V any](data map[K]V) []K {
+	keys := make([]K, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// WriteTo writes the metrics in prometheus text format
+func (m *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	m.mutex.Lock()
+
+	name := m.namespace + "_requests_total"
+	fmt.Fprintf(buf, "# HELP %s Total number of http requests.\n", name)
+	fmt.Fprintf(buf, "# TYPE %s counter\n", name)
+	for _, labels := range sortedKeys(m.requests) {
+		fmt.Fprintf(buf, "%s{%s} %d\n", name, labels, m.requests[labels])
+	}
+
+	name = m.namespace + "_request_duration_seconds"
+	fmt.Fprintf(buf, "# HELP %s Latency of http requests in seconds.\n", name)
+	fmt.Fprintf(buf, "# TYPE %s histogram\n", name)
+	for _, labels := range sortedKeys(m.durations) {
+		m.writeHistogram(buf, name, labels.String(), m.durations[labels])
+	}
+
+	name = m.namespace + "_request_phase_duration_seconds"
+	fmt.Fprintf(buf, "# HELP %s Latency of dns, tcp and tls phases in seconds.\n", name)
+	fmt.Fprintf(buf, "# TYPE %s histogram\n", name)
+	for _, labels := range sortedKeys(m.phases) {
+		m.writeHistogram(buf, name, labels.String(), m.phases[labels])
+	}
+
+	m.mutex.Unlock()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP exposes the metrics in prometheus text format
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(headerContentType, metricsContentType)
+	_, _ = m.WriteTo(w)
+}
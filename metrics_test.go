@@ -0,0 +1,102 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	HT "github.com/vicanso/http-trace"
+)
+
+func TestMetricsLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`method="GET",route="/a\"b\\c\n"`, formatMetricsLabels("method", "GET", "route", "/a\"b\\c\n"))
+	assert.Equal("0.005", formatMetricsFloat(0.005))
+	assert.Equal("10", formatMetricsFloat(10))
+}
+
+func TestMetricsCollector(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMetricsCollector(MetricsConfig{
+		Buckets: []float64{1, 0.1},
+	})
+	ins := NewInstance(&InstanceConfig{
+		Adapter: func(config *Config) (*Response, error) {
+			if config.Route == "/error" {
+				return nil, errors.New("custom error")
+			}
+			return &Response{
+				Status: 200,
+			}, nil
+		},
+	})
+	m.Register(ins)
+
+	_, err := ins.Request(&Config{
+		Route: "/users/:type",
+		URL:   "/users/me",
+	})
+	assert.Nil(err)
+	_, err = ins.Get("/error")
+	assert.NotNil(err)
+
+	// 启用trace时记录dns、tcp与tls的耗时
+	m.OnDone(&Config{
+		Method: "GET",
+		Route:  "/trace",
+		HTTPTrace: &HT.HTTPTrace{
+			Start:    time.Unix(1, 0),
+			DNSStart: time.Unix(1, 0),
+			DNSDone:  time.Unix(1, int64(50*time.Millisecond)),
+			Done:     time.Unix(2, 0),
+		},
+	}, &Response{
+		Status: 204,
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	assert.Equal(http.StatusOK, resp.Code)
+	assert.Equal(metricsContentType, resp.Header().Get(headerContentType))
+
+	body := resp.Body.String()
+	for _, line := range []string{
+		"# TYPE axios_requests_total counter",
+		`axios_requests_total{method="GET",route="/error",status="-1",category="other"} 1`,
+		`axios_requests_total{method="GET",route="/users/:type",status="200",category=""} 1`,
+		`axios_requests_total{method="GET",route="/trace",status="204",category=""} 1`,
+		"# TYPE axios_request_duration_seconds histogram",
+		`axios_request_duration_seconds_bucket{method="GET",route="/users/:type",status="200",category="",le="0.1"} 1`,
+		`axios_request_duration_seconds_bucket{method="GET",route="/users/:type",status="200",category="",le="+Inf"} 1`,
+		`axios_request_duration_seconds_count{method="GET",route="/users/:type",status="200",category=""} 1`,
+		`axios_request_duration_seconds_bucket{method="GET",route="/trace",status="204",category="",le="0.1"} 0`,
+		`axios_request_duration_seconds_bucket{method="GET",route="/trace",status="204",category="",le="1"} 1`,
+		`axios_request_duration_seconds_sum{method="GET",route="/trace",status="204",category=""} 1`,
+		`axios_request_phase_duration_seconds_bucket{method="GET",route="/trace",phase="dns",le="0.1"} 1`,
+		`axios_request_phase_duration_seconds_sum{method="GET",route="/trace",phase="dns"} 0.05`,
+	} {
+		assert.Contains(body, line+"\n")
+	}
+	assert.False(strings.Contains(body, `phase="tcp"`))
+}
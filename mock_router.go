@@ -0,0 +1,351 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrMockNotMatched = errors.New("no mock route is matched")
+
+type (
+	// TestingT the interface of *testing.T used by mock router
+	TestingT interface {
+		Helper()
+		Errorf(format string, args ...interface{})
+	}
+
+	mockReply struct {
+		resp *Response
+		err  error
+	}
+
+	// MockRoute mock route of router
+	MockRoute struct {
+		router   *MockRouter
+		method   string
+		pattern  string
+		matchers []func(config *Config, body []byte) bool
+		replies  []*mockReply
+		delay    time.Duration
+		times    int
+		calls    int
+	}
+
+	// MockRouter mock router which matches the request by method, route,
+	// query, headers and body, it's used with CustomMock
+	MockRouter struct {
+		mutex      sync.Mutex
+		routes     []*MockRoute
+		unexpected []string
+	}
+)
+
+// NewMockRouter creates a mock router
+func NewMockRouter() *MockRouter {
+	return &MockRouter{}
+}
+
+// On adds a mock route, the method matches any method if it's empty or "*",
+// the pattern matches the route of config or the path of request url,
+// and supports ":name" for one segment and "*" for the rest segments
+func (r *MockRouter) On(method, pattern string) *MockRoute {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	route := &MockRoute{
+		router:  r,
+		method:  strings.ToUpper(method),
+		pattern: pattern,
+	}
+	r.routes = append(r.routes, route)
+	return route
+}
+
+// Mock mocks the adapter of instance, call done to restore
+func (r *MockRouter) Mock(ins *Instance) (done func()) {
+	return ins.CustomMock(r.Handle)
+}
+
+// Handle handles the request, it's a CustomMocker
+func (r *MockRouter) Handle(config *Config) (*Response, error) {
+	var body []byte
+	req := config.Request
+	if req != nil && req.Body != nil {
+		buf, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = buf
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	r.mutex.Lock()
+	var matched *MockRoute
+	for _, route := range r.routes {
+		if route.times > 0 && route.calls >= route.times {
+			continue
+		}
+		if route.match(config, body) {
+			matched = route
+			break
+		}
+	}
+	if matched == nil {
+		r.unexpected = append(r.unexpected, fmt.Sprintf("%s %s", config.Method, config.GetURL()))
+		r.mutex.Unlock()
+		return nil, ErrMockNotMatched
+	}
+	matched.calls++
+	reply := matched.reply(matched.calls)
+	delay := matched.delay
+	r.mutex.Unlock()
+
+	if delay > 0 {
+		ctx := config.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if reply == nil {
+		return &Response{
+			Status: http.StatusOK,
+		}, nil
+	}
+	if reply.err != nil {
+		return nil, reply.err
+	}
+	// 复制响应，避免多次请求时transform影响原数据
+	resp := *reply.resp
+	resp.Headers = reply.resp.Headers.Clone()
+	if resp.Headers == nil {
+		resp.Headers = make(http.Header)
+	}
+	return &resp, nil
+}
+
+// AssertExpectations asserts that all expectations of routes are met and
+// there is no unexpected call
+func (r *MockRouter) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ok := true
+	for _, route := range r.routes {
+		if route.times > 0 && route.calls != route.times {
+			ok = false
+			t.Errorf("mock route %s %s expected %d calls, but got %d", route.method, route.pattern, route.times, route.calls)
+			continue
+		}
+		if route.calls == 0 {
+			ok = false
+			t.Errorf("mock route %s %s is not called", route.method, route.pattern)
+		}
+	}
+	for _, call := range r.unexpected {
+		ok = false
+		t.Errorf("unexpected call: %s", call)
+	}
+	return ok
+}
+
+// matchPath returns true if the path matches the pattern
+func matchPath(pattern, path string) bool {
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	paths := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range patterns {
+		if p == "*" {
+			return true
+		}
+		if i >= len(paths) {
+			return false
+		}
+		if strings.HasPrefix(p, ":") {
+			continue
+		}
+		if p != paths[i] {
+			return false
+		}
+	}
+	return len(patterns) == len(paths)
+}
+
+func (route *MockRoute) match(config *Config, body []byte) bool {
+	if route.method != "" && route.method != "*" && route.method != strings.ToUpper(config.Method) {
+		return false
+	}
+	if route.pattern != config.Route {
+		path := ""
+		if config.Request != nil {
+			path = config.Request.URL.Path
+		} else if urlInfo, _ := url.Parse(config.GetURL()); urlInfo != nil {
+			path = urlInfo.Path
+		}
+		if !matchPath(route.pattern, path) {
+			return false
+		}
+	}
+	for _, fn := range route.matchers {
+		if !fn(config, body) {
+			return false
+		}
+	}
+	return true
+}
+
+// reply returns the reply of the nth call, the last reply is used if the replies are exhausted
+func (route *MockRoute) reply(n int) *mockReply {
+	if len(route.replies) == 0 {
+		return nil
+	}
+	if n > len(route.replies) {
+		n = len(route.replies)
+	}
+	return route.replies[n-1]
+}
+
+func getRequestQuery(config *Config) url.Values {
+	if config.Request != nil {
+		return config.Request.URL.Query()
+	}
+	return config.Query
+}
+
+func getRequestHeader(config *Config) http.Header {
+	if config.Request != nil {
+		return config.Request.Header
+	}
+	return config.Headers
+}
+
+// WithQuery matches the query value of request
+func (route *MockRoute) WithQuery(key, value string) *MockRoute {
+	return route.WithQueryFunc(func(query url.Values) bool {
+		return query.Get(key) == value
+	})
+}
+
+// WithQueryFunc matches the query of request by predicate
+func (route *MockRoute) WithQueryFunc(fn func(query url.Values) bool) *MockRoute {
+	route.matchers = append(route.matchers, func(config *Config, _ []byte) bool {
+		return fn(getRequestQuery(config))
+	})
+	return route
+}
+
+// WithHeader matches the header value of request
+func (route *MockRoute) WithHeader(key, value string) *MockRoute {
+	return route.WithHeaderFunc(func(header http.Header) bool {
+		return header.Get(key) == value
+	})
+}
+
+// WithHeaderFunc matches the header of request by predicate
+func (route *MockRoute) WithHeaderFunc(fn func(header http.Header) bool) *MockRoute {
+	route.matchers = append(route.matchers, func(config *Config, _ []byte) bool {
+		return fn(getRequestHeader(config))
+	})
+	return route
+}
+
+// WithJSONBody matches the json body of request, it's equal
+// if both of them are unmarshaled to the same value
+func (route *MockRoute) WithJSONBody(value interface{}) *MockRoute {
+	buf, err := jsonMarshal(value)
+	var expected interface{}
+	if err == nil {
+		err = jsonUnmarshal(buf, &expected)
+	}
+	route.matchers = append(route.matchers, func(_ *Config, body []byte) bool {
+		if err != nil {
+			return false
+		}
+		var actual interface{}
+		if jsonUnmarshal(body, &actual) != nil {
+			return false
+		}
+		return reflect.DeepEqual(expected, actual)
+	})
+	return route
+}
+
+// Reply adds the response to the reply sequence
+func (route *MockRoute) Reply(resp *Response) *MockRoute {
+	route.replies = append(route.replies, &mockReply{
+		resp: resp,
+	})
+	return route
+}
+
+// ReplyJSON adds the json response to the reply sequence
+func (route *MockRoute) ReplyJSON(status int, value interface{}) *MockRoute {
+	buf, err := jsonMarshal(value)
+	if err != nil {
+		return route.ReplyError(err)
+	}
+	return route.Reply(&Response{
+		Status: status,
+		Headers: http.Header{
+			headerContentType: []string{
+				contentTypeJSON,
+			},
+		},
+		Data: buf,
+	})
+}
+
+// ReplyError adds the error to the reply sequence
+func (route *MockRoute) ReplyError(err error) *MockRoute {
+	route.replies = append(route.replies, &mockReply{
+		err: err,
+	})
+	return route
+}
+
+// Delay sets the latency of response
+func (route *MockRoute) Delay(d time.Duration) *MockRoute {
+	route.delay = d
+	return route
+}
+
+// Times sets the expected calls of route, the route will not be matched
+// after it's called n times, 0 means at least once
+func (route *MockRoute) Times(n int) *MockRoute {
+	route.times = n
+	return route
+}
+
+// Calls returns the count of calls
+func (route *MockRoute) Calls() int {
+	route.router.mutex.Lock()
+	defer route.router.mutex.Unlock()
+	return route.calls
+}
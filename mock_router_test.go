@@ -0,0 +1,150 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTestingT struct {
+	errors []string
+}
+
+func (t *mockTestingT) Helper() {}
+
+func (t *mockTestingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMatchPath(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(matchPath("/users/me", "/users/me"))
+	assert.True(matchPath("/users/:type", "/users/me"))
+	assert.True(matchPath("/users/*", "/users/me/books"))
+	assert.False(matchPath("/users/:type", "/users/me/books"))
+	assert.False(matchPath("/users/:type/books", "/users/me"))
+	assert.False(matchPath("/books/:type", "/users/me"))
+}
+
+func TestMockRouter(t *testing.T) {
+	assert := assert.New(t)
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: "https://aslant.site",
+	})
+	router := NewMockRouter()
+	done := router.Mock(ins)
+	defer done()
+
+	customErr := errors.New("custom error")
+	users := router.On("GET", "/users/:type").
+		WithQuery("cache", "false").
+		WithHeader("X-Token", "abc").
+		ReplyJSON(200, map[string]string{
+			"name": "tree",
+		}).
+		ReplyError(customErr).
+		Times(2)
+	books := router.On("POST", "/books").
+		WithJSONBody(map[string]interface{}{
+			"name":  "go",
+			"price": 10,
+		}).
+		Reply(&Response{
+			Status: 201,
+		})
+
+	resp, err := ins.Request(&Config{
+		URL: "/users/me",
+		Query: url.Values{
+			"cache": []string{"false"},
+		},
+		Headers: http.Header{
+			"X-Token": []string{"abc"},
+		},
+	})
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.Equal(`{"name":"tree"}`, string(resp.Data))
+
+	_, err = ins.Request(&Config{
+		URL: "/users/me?cache=false",
+		Headers: http.Header{
+			"X-Token": []string{"abc"},
+		},
+	})
+	assert.True(errors.Is(err, customErr))
+	assert.Equal(2, users.Calls())
+
+	resp, err = ins.Post("/books", map[string]interface{}{
+		"price": 10,
+		"name":  "go",
+	})
+	assert.Nil(err)
+	assert.Equal(201, resp.Status)
+	assert.Equal(1, books.Calls())
+
+	mt := &mockTestingT{}
+	assert.True(router.AssertExpectations(mt))
+	assert.Empty(mt.errors)
+
+	// 超过调用次数或body不匹配
+	_, err = ins.Get("/users/me?cache=false")
+	assert.True(errors.Is(err, ErrMockNotMatched))
+	_, err = ins.Post("/books", map[string]string{
+		"name": "go",
+	})
+	assert.True(errors.Is(err, ErrMockNotMatched))
+
+	router.On("DELETE", "/books/:id")
+	assert.False(router.AssertExpectations(mt))
+	assert.Equal([]string{
+		"mock route DELETE /books/:id is not called",
+		"unexpected call: GET https://aslant.site/users/me?cache=false",
+		"unexpected call: POST https://aslant.site/books",
+	}, mt.errors)
+}
+
+func TestMockRouterDelay(t *testing.T) {
+	assert := assert.New(t)
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: "https://aslant.site",
+	})
+	router := NewMockRouter()
+	done := router.Mock(ins)
+	defer done()
+
+	router.On("*", "/users/me").
+		Delay(50 * time.Millisecond)
+
+	start := time.Now()
+	resp, err := ins.Get("/users/me")
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.True(time.Since(start) >= 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ins.GetX(ctx, "/users/me")
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+}
@@ -16,6 +16,7 @@ package axios
 
 import (
 	"bytes"
+	"io"
 	"mime/multipart"
 )
 
@@ -73,3 +74,12 @@ func (m *multipartFile) Bytes() ([]byte, error) {
 	}
 	return m.data.Bytes(), nil
 }
+
+// Reader returns reader of bytes
+func (m *multipartFile) Reader() (io.Reader, error) {
+	data, err := m.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
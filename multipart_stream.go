@@ -0,0 +1,211 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const contentTypeOctetStream = "application/octet-stream"
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+type (
+	// MultipartBody multipart body for upload
+	MultipartBody interface {
+		// FormDataContentType returns the content type of body
+		FormDataContentType() string
+		// Reader returns the reader of body
+		Reader() (io.Reader, error)
+	}
+
+	multipartPart struct {
+		header textproto.MIMEHeader
+		reader io.Reader
+		// 文件路径，在写入时才打开
+		path string
+	}
+	multipartStream struct {
+		boundary string
+		parts    []*multipartPart
+	}
+
+	// lazyPipeReader starts writing data to pipe when it's read first time
+	lazyPipeReader struct {
+		once   sync.Once
+		reader *io.PipeReader
+		write  func()
+	}
+)
+
+// NewMultipartStream creates a multipart writer which writes the parts
+// to the request body lazily, the data of parts is not loaded into memory
+func NewMultipartStream() *multipartStream {
+	return &multipartStream{
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+func newFormDataHeader(field, filename, contentType string) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(field))
+	if filename != "" {
+		disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filename))
+		if contentType == "" {
+			contentType = contentTypeOctetStream
+		}
+	}
+	h.Set("Content-Disposition", disposition)
+	if contentType != "" {
+		h.Set(headerContentType, contentType)
+	}
+	return h
+}
+
+// SetBoundary sets the boundary of multipart
+func (m *multipartStream) SetBoundary(boundary string) error {
+	// 使用multipart writer校验boundary是否合法
+	err := multipart.NewWriter(io.Discard).SetBoundary(boundary)
+	if err != nil {
+		return err
+	}
+	m.boundary = boundary
+	return nil
+}
+
+// AddPart adds part with custom headers
+func (m *multipartStream) AddPart(header textproto.MIMEHeader, r io.Reader) {
+	m.parts = append(m.parts, &multipartPart{
+		header: header,
+		reader: r,
+	})
+}
+
+// AddFields adds fields to writer
+func (m *multipartStream) AddFields(fields map[string]string) {
+	for k, v := range fields {
+		m.AddPart(newFormDataHeader(k, "", ""), strings.NewReader(v))
+	}
+}
+
+// AddReader adds file from reader, the content type is application/octet-stream if it's not set
+func (m *multipartStream) AddReader(field, filename string, r io.Reader, contentType ...string) {
+	ct := ""
+	if len(contentType) != 0 {
+		ct = contentType[0]
+	}
+	m.AddPart(newFormDataHeader(field, filename, ct), r)
+}
+
+// AddFilePath adds file from path, the file is opened when the body is written
+func (m *multipartStream) AddFilePath(field, path string, contentType ...string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	ct := ""
+	if len(contentType) != 0 {
+		ct = contentType[0]
+	}
+	m.parts = append(m.parts, &multipartPart{
+		header: newFormDataHeader(field, filepath.Base(path), ct),
+		path:   path,
+	})
+	return nil
+}
+
+// FormDataContentType returns content type
+func (m *multipartStream) FormDataContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+func (p *multipartPart) writeTo(w io.Writer) error {
+	r := p.reader
+	if p.path != "" {
+		f, err := os.Open(p.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if r == nil {
+		return nil
+	}
+	_, err := io.Copy(w, r)
+	return err
+}
+
+func (m *multipartStream) writeTo(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	err := writer.SetBoundary(m.boundary)
+	if err != nil {
+		return err
+	}
+	for _, p := range m.parts {
+		pw, err := writer.CreatePart(p.header)
+		if err != nil {
+			return err
+		}
+		err = p.writeTo(pw)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// multipartBodyData returns the data of multipart body for request, the buffered
+// body(e.g. multipart file) is returned as bytes, so it could be read again for retry or curl
+func multipartBodyData(file MultipartBody) (interface{}, error) {
+	if b, ok := file.(interface{ Bytes() ([]byte, error) }); ok {
+		return b.Bytes()
+	}
+	return file.Reader()
+}
+
+// Reader returns the reader of multipart body, the data is written
+// to the pipe when it's read, so the reader of part can only be read once
+func (m *multipartStream) Reader() (io.Reader, error) {
+	pr, pw := io.Pipe()
+	return &lazyPipeReader{
+		reader: pr,
+		write: func() {
+			_ = pw.CloseWithError(m.writeTo(pw))
+		},
+	}, nil
+}
+
+func (r *lazyPipeReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		go r.write()
+	})
+	return r.reader.Read(p)
+}
+
+// Close closes the pipe, the writing will be aborted
+func (r *lazyPipeReader) Close() error {
+	return r.reader.Close()
+}
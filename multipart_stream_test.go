@@ -0,0 +1,97 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartStream(t *testing.T) {
+	assert := assert.New(t)
+
+	file := filepath.Join(t.TempDir(), "test.txt")
+	err := os.WriteFile(file, []byte("Hello World!"), 0600)
+	assert.Nil(err)
+
+	ms := NewMultipartStream()
+	assert.Nil(ms.SetBoundary("abc"))
+	assert.Nil(ms.AddFilePath("file", file))
+	assert.NotNil(ms.AddFilePath("file", filepath.Join(t.TempDir(), "not-found")))
+	ms.AddReader("data", "data.json", strings.NewReader(`{"a":1}`), "application/json")
+	ms.AddFields(map[string]string{
+		"type": "vip",
+	})
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="custom"`)
+	header.Set("X-Custom", "1")
+	ms.AddPart(header, strings.NewReader("custom"))
+
+	assert.Equal("multipart/form-data; boundary=abc", ms.FormDataContentType())
+
+	r, err := ms.Reader()
+	assert.Nil(err)
+	data, err := io.ReadAll(r)
+	assert.Nil(err)
+	assert.Equal("--abc\r\nContent-Disposition: form-data; name=\"file\"; filename=\"test.txt\"\r\nContent-Type: application/octet-stream\r\n\r\nHello World!\r\n--abc\r\nContent-Disposition: form-data; name=\"data\"; filename=\"data.json\"\r\nContent-Type: application/json\r\n\r\n{\"a\":1}\r\n--abc\r\nContent-Disposition: form-data; name=\"type\"\r\n\r\nvip\r\n--abc\r\nContent-Disposition: form-data; name=\"custom\"\r\nX-Custom: 1\r\n\r\ncustom\r\n--abc--\r\n", string(data))
+
+	// 未读取前关闭
+	r, err = ms.Reader()
+	assert.Nil(err)
+	assert.Nil(r.(io.Closer).Close())
+	_, err = r.Read(make([]byte, 10))
+	assert.Equal(io.ErrClosedPipe, err)
+}
+
+func TestUploadMultipartStream(t *testing.T) {
+	assert := assert.New(t)
+
+	ins := NewInstance(&InstanceConfig{
+		Adapter: func(config *Config) (*Response, error) {
+			req := config.Request
+			_, params, err := mime.ParseMediaType(req.Header.Get(headerContentType))
+			if err != nil {
+				return nil, err
+			}
+			mr := multipart.NewReader(req.Body, params["boundary"])
+			part, err := mr.NextPart()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			return &Response{
+				Status: http.StatusOK,
+				Data:   []byte(part.FileName() + ":" + string(data)),
+			}, nil
+		},
+	})
+	ms := NewMultipartStream()
+	ms.AddReader("file", "a.txt", strings.NewReader("abc"))
+	resp, err := ins.Upload("/upload", ms)
+	assert.Nil(err)
+	assert.Equal("a.txt:abc", string(resp.Data))
+}
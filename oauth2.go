@@ -0,0 +1,320 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OAuth2GrantClientCredentials client credentials grant
+	OAuth2GrantClientCredentials = "client_credentials"
+	// OAuth2GrantRefreshToken refresh token grant
+	OAuth2GrantRefreshToken = "refresh_token"
+
+	headerAuthorization = "Authorization"
+	// oauth2ExpiryDelta the token is refreshed before it's expired
+	oauth2ExpiryDelta = 10 * time.Second
+	// oauth2Timeout default timeout of token request
+	oauth2Timeout = 30 * time.Second
+)
+
+type (
+	// OAuth2Config config of oauth2
+	OAuth2Config struct {
+		// TokenURL the url of token endpoint
+		TokenURL string
+		// ClientID client id
+		ClientID string
+		// ClientSecret client secret
+		ClientSecret string
+		// Scopes scopes of token
+		Scopes []string
+		// GrantType grant type, default is client credentials
+		GrantType string
+		// RefreshToken refresh token for refresh token grant
+		RefreshToken string
+		// AuthInParams sends the client id and secret in form params instead of basic auth
+		AuthInParams bool
+		// ExpiryDelta the token is refreshed before it's expired, default is 10s
+		ExpiryDelta time.Duration
+		// Timeout the timeout of token request, default is 30s
+		Timeout time.Duration
+		// Instance the instance to request token, default is the default instance
+		Instance *Instance
+	}
+
+	// OAuth2Token token of oauth2
+	OAuth2Token struct {
+		AccessToken  string    `json:"access_token"`
+		TokenType    string    `json:"token_type,omitempty"`
+		RefreshToken string    `json:"refresh_token,omitempty"`
+		ExpiresIn    int64     `json:"expires_in,omitempty"`
+		Expiry       time.Time `json:"-"`
+	}
+
+	// OAuth2Error error of token endpoint
+	OAuth2Error struct {
+		// Status status of response
+		Status int
+		// Code the error code of response
+		Code string `json:"error"`
+		// Description the error description of response
+		Description string `json:"error_description"`
+	}
+
+	oauth2Call struct {
+		done  chan struct{}
+		token *OAuth2Token
+		err   error
+	}
+
+	// OAuth2 oauth2 authentication, the token is cached until it's expired
+	OAuth2 struct {
+		mutex        sync.Mutex
+		config       OAuth2Config
+		token        *OAuth2Token
+		refreshToken string
+		call         *oauth2Call
+	}
+)
+
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("oauth2 token request fail, status: %d", e.Status)
+	if e.Code != "" {
+		msg += ", error: " + e.Code
+	}
+	if e.Description != "" {
+		msg += ", description: " + e.Description
+	}
+	return msg
+}
+
+// NewOAuth2 creates an oauth2 authentication
+func NewOAuth2(config OAuth2Config) *OAuth2 {
+	if config.GrantType == "" {
+		config.GrantType = OAuth2GrantClientCredentials
+	}
+	if config.ExpiryDelta == 0 {
+		config.ExpiryDelta = oauth2ExpiryDelta
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = oauth2Timeout
+	}
+	if config.Instance == nil {
+		config.Instance = defaultIns
+	}
+	return &OAuth2{
+		config:       config,
+		refreshToken: config.RefreshToken,
+	}
+}
+
+// valid returns true if the token is not expired
+func (t *OAuth2Token) valid(delta time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry)
+}
+
+// authorization returns the value of authorization header
+func (t *OAuth2Token) authorization() string {
+	tokenType := t.TokenType
+	// bearer的大小写不一致时统一为Bearer
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// Token returns the cached token, or fetches a new one if it's expired,
+// there is only one in-flight request of token
+func (o *OAuth2) Token(ctx context.Context) (*OAuth2Token, error) {
+	o.mutex.Lock()
+	if o.token.valid(o.config.ExpiryDelta) {
+		token := o.token
+		o.mutex.Unlock()
+		return token, nil
+	}
+	call := o.call
+	if call == nil {
+		call = &oauth2Call{
+			done: make(chan struct{}),
+		}
+		o.call = call
+		refreshToken := o.refreshToken
+		go o.fetch(call, refreshToken)
+	}
+	o.mutex.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.token, call.err
+	}
+}
+
+// Invalidate invalidates the token if it's the current one
+func (o *OAuth2) Invalidate(accessToken string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.token != nil && o.token.AccessToken == accessToken {
+		o.token = nil
+	}
+}
+
+// fetch requests the token from token endpoint, it's not canceled by
+// the context of request as the token is shared by all requests,
+// but it's bounded by the timeout of config, so the call is always cleared
+func (o *OAuth2) fetch(call *oauth2Call, refreshToken string) {
+	var token *OAuth2Token
+	var err error
+	if refreshToken != "" {
+		token, err = o.requestToken(url.Values{
+			"grant_type":    []string{OAuth2GrantRefreshToken},
+			"refresh_token": []string{refreshToken},
+		})
+	}
+	// 刷新失败时使用client credentials重新获取
+	if (refreshToken == "" || err != nil) && o.config.GrantType == OAuth2GrantClientCredentials {
+		token, err = o.requestToken(url.Values{
+			"grant_type": []string{OAuth2GrantClientCredentials},
+		})
+	}
+	if err == nil && token == nil {
+		err = &OAuth2Error{
+			Code: "missing refresh token",
+		}
+	}
+
+	o.mutex.Lock()
+	if err == nil {
+		o.token = token
+		if token.RefreshToken != "" {
+			o.refreshToken = token.RefreshToken
+		}
+	}
+	o.call = nil
+	o.mutex.Unlock()
+
+	call.token = token
+	call.err = err
+	close(call.done)
+}
+
+func (o *OAuth2) requestToken(params url.Values) (*OAuth2Token, error) {
+	if len(o.config.Scopes) != 0 {
+		params.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+	headers := make(http.Header)
+	if o.config.AuthInParams {
+		params.Set("client_id", o.config.ClientID)
+		if o.config.ClientSecret != "" {
+			params.Set("client_secret", o.config.ClientSecret)
+		}
+	} else if o.config.ClientID != "" {
+		// client id与secret需先url编码
+		credentials := url.QueryEscape(o.config.ClientID) + ":" + url.QueryEscape(o.config.ClientSecret)
+		headers.Set(headerAuthorization, "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	resp, err := o.config.Instance.Request(&Config{
+		URL:     o.config.TokenURL,
+		Method:  http.MethodPost,
+		Headers: headers,
+		Body:    params,
+		Timeout: o.config.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !isSuccessStatus(resp.Status) {
+		oe := &OAuth2Error{}
+		_ = resp.JSON(oe)
+		oe.Status = resp.Status
+		return nil, oe
+	}
+	token := &OAuth2Token{}
+	err = resp.JSON(token)
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, &OAuth2Error{
+			Status: resp.Status,
+			Code:   "missing access token",
+		}
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// RequestInterceptor sets the authorization header of request
+func (o *OAuth2) RequestInterceptor(config *Config) error {
+	token, err := o.Token(config.Context)
+	if err != nil {
+		return err
+	}
+	config.Request.Header.Set(headerAuthorization, token.authorization())
+	return nil
+}
+
+// Attach attaches the oauth2 to instance config, the request interceptor
+// is appended, and the adapter of each request(including mock) is wrapped
+// to invalidate the token and retry once if the response is 401
+func (o *OAuth2) Attach(conf *InstanceConfig) {
+	conf.RequestInterceptors = append(conf.RequestInterceptors, o.RequestInterceptor)
+	conf.addAdapterWrapper(o.wrapAdapter)
+}
+
+// wrapAdapter wraps the adapter to refresh the token and retry once if the response is 401
+func (o *OAuth2) wrapAdapter(adapter Adapter) Adapter {
+	return func(config *Config) (*Response, error) {
+		resp, err := adapter(config)
+		if err != nil || resp == nil || resp.Status != http.StatusUnauthorized {
+			return resp, err
+		}
+		req, ok := config.replayRequest()
+		if !ok {
+			return resp, nil
+		}
+		// 401的响应不再返回，关闭其body
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		_, accessToken, _ := strings.Cut(config.Request.Header.Get(headerAuthorization), " ")
+		o.Invalidate(accessToken)
+		token, err := o.Token(config.Context)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(headerAuthorization, token.authorization())
+		config.Request = req
+		return adapter(config)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newOAuth2TestServer(count *int32, grants *[]string) *httptest.Server {
+	var mutex sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(count, 1)
+		_ = r.ParseForm()
+		mutex.Lock()
+		*grants = append(*grants, r.Form.Get("grant_type"))
+		mutex.Unlock()
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "" && (clientID != "id" || clientSecret != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if r.Form.Get("grant_type") == OAuth2GrantRefreshToken && r.Form.Get("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set(headerContentType, contentTypeJSON)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600,"refresh_token":"refresh"}`, n)
+	}))
+}
+
+func TestOAuth2Token(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	grants := make([]string, 0)
+	server := newOAuth2TestServer(&count, &grants)
+	defer server.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes: []string{
+			"read",
+		},
+	})
+
+	// 并发获取token时只请求一次
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := o.Token(context.Background())
+			assert.Nil(err)
+			assert.Equal("token-1", token.AccessToken)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+
+	token, err := o.Token(context.Background())
+	assert.Nil(err)
+	assert.Equal("Bearer token-1", token.authorization())
+	assert.True(token.valid(time.Minute))
+	assert.False(token.valid(2 * time.Hour))
+
+	// 非当前token不影响
+	o.Invalidate("token-0")
+	token, _ = o.Token(context.Background())
+	assert.Equal("token-1", token.AccessToken)
+
+	// 失效后使用refresh token刷新
+	o.Invalidate("token-1")
+	token, err = o.Token(context.Background())
+	assert.Nil(err)
+	assert.Equal("token-2", token.AccessToken)
+	assert.Equal([]string{
+		OAuth2GrantClientCredentials,
+		OAuth2GrantRefreshToken,
+	}, grants)
+
+	o = NewOAuth2(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "id",
+		ClientSecret: "invalid",
+	})
+	_, err = o.Token(context.Background())
+	oe := &OAuth2Error{}
+	assert.True(errors.As(err, &oe))
+	assert.Equal(401, oe.Status)
+	assert.Equal("oauth2 token request fail, status: 401, error: invalid_client", oe.Error())
+
+	o = NewOAuth2(OAuth2Config{
+		TokenURL:     server.URL,
+		GrantType:    OAuth2GrantRefreshToken,
+		RefreshToken: "invalid",
+		AuthInParams: true,
+	})
+	_, err = o.Token(context.Background())
+	assert.True(errors.As(err, &oe))
+	assert.Equal("invalid_grant", oe.Code)
+}
+
+func TestOAuth2TokenTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 首次请求无响应
+		if atomic.AddInt32(&count, 1) == 1 {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Header().Set(headerContentType, "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+	}))
+	defer server.Close()
+	defer close(done)
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL: server.URL,
+		ClientID: "id",
+		Timeout:  50 * time.Millisecond,
+		Instance: NewInstance(nil),
+	})
+	_, err := o.Token(nil)
+	assert.NotNil(err)
+	assert.Equal(ErrCategoryTimeout, GetInternalErrorCategory(err))
+
+	// 超时后重新获取token
+	token, err := o.Token(context.Background())
+	assert.Nil(err)
+	assert.Equal("token", token.AccessToken)
+	assert.Equal(int32(2), atomic.LoadInt32(&count))
+}
+
+func TestOAuth2Attach(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	grants := make([]string, 0)
+	server := newOAuth2TestServer(&count, &grants)
+	defer server.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+	})
+	var authorizations []string
+	conf := &InstanceConfig{
+		Adapter: func(config *Config) (*Response, error) {
+			authorization := config.Request.Header.Get(headerAuthorization)
+			authorizations = append(authorizations, authorization)
+			var data []byte
+			if config.Request.Body != nil {
+				data, _ = io.ReadAll(config.Request.Body)
+			}
+			status := 200
+			// 第一个token已失效
+			if authorization == "Bearer token-1" {
+				status = 401
+			}
+			return &Response{
+				Status: status,
+				Data:   data,
+			}, nil
+		},
+	}
+	o.Attach(conf)
+	ins := NewInstance(conf)
+
+	resp, err := ins.Post("/", map[string]string{
+		"name": "tree",
+	})
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.Equal(`{"name":"tree"}`, string(resp.Data))
+	assert.Equal([]string{
+		"Bearer token-1",
+		"Bearer token-2",
+	}, authorizations)
+
+	resp, err = ins.Get("/")
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.Equal("Bearer token-2", authorizations[2])
+	assert.Equal(int32(2), atomic.LoadInt32(&count))
+
+	// mock的adapter同样处理401
+	done := ins.CustomMock(func(config *Config) (*Response, error) {
+		status := 200
+		if config.Request.Header.Get(headerAuthorization) == "Bearer token-2" {
+			status = 401
+		}
+		return &Response{
+			Status: status,
+		}, nil
+	})
+	defer done()
+	resp, err = ins.Get("/")
+	assert.Nil(err)
+	assert.Equal(200, resp.Status)
+	assert.Equal(int32(3), atomic.LoadInt32(&count))
+}
+
+type oauth2TestBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *oauth2TestBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestOAuth2AttachRefreshFail(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	grants := make([]string, 0)
+	server := newOAuth2TestServer(&count, &grants)
+	defer server.Close()
+
+	o := NewOAuth2(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "id",
+		ClientSecret: "invalid",
+	})
+	o.token = &OAuth2Token{
+		AccessToken: "token",
+	}
+	body := &oauth2TestBody{
+		Reader: strings.NewReader("unauthorized"),
+	}
+	conf := &InstanceConfig{
+		Adapter: func(config *Config) (*Response, error) {
+			return &Response{
+				Status: 401,
+				Body:   body,
+			}, nil
+		},
+	}
+	o.Attach(conf)
+	_, err := NewInstance(conf).Get("/")
+	oe := &OAuth2Error{}
+	assert.True(errors.As(err, &oe))
+	// 获取token失败时关闭401响应的body
+	assert.True(body.closed)
+
+	// adapter返回空响应
+	assert.NotPanics(func() {
+		resp, err := o.wrapAdapter(func(config *Config) (*Response, error) {
+			return nil, nil
+		})(&Config{})
+		assert.Nil(resp)
+		assert.Nil(err)
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultProgressInterval default interval of progress callback
+const defaultProgressInterval = 200 * time.Millisecond
+
+type (
+	// Progress progress of request body or response body
+	Progress struct {
+		// Transferred the size of data which has been transferred
+		Transferred int64
+		// Total the total size of data, it's -1 if it's unknown
+		Total int64
+		// Rate the average rate(bytes per second) since the transfer started
+		Rate float64
+		// Done the transfer is completed
+		Done bool
+	}
+	// OnProgress on progress event
+	OnProgress func(progress *Progress)
+
+	// progressReader reports the progress when the data is read
+	progressReader struct {
+		mutex       sync.Mutex
+		r           io.ReadCloser
+		fn          OnProgress
+		interval    time.Duration
+		total       int64
+		transferred int64
+		startedAt   time.Time
+		reportedAt  time.Time
+		done        bool
+	}
+)
+
+// newProgressReader wraps the body to report the progress, the fn is called at interval
+// and when the data is read completely
+func newProgressReader(r io.ReadCloser, total int64, interval time.Duration, fn OnProgress) *progressReader {
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	if total < 0 {
+		total = -1
+	}
+	now := time.Now()
+	return &progressReader{
+		r:          r,
+		fn:         fn,
+		interval:   interval,
+		total:      total,
+		startedAt:  now,
+		reportedAt: now,
+	}
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.mutex.Lock()
+	if pr.done {
+		pr.mutex.Unlock()
+		return n, err
+	}
+	pr.transferred += int64(n)
+	now := time.Now()
+	// 读取完成或已读取所有数据时，标记为完成
+	done := err == io.EOF || (pr.total >= 0 && pr.transferred >= pr.total)
+	if !done && now.Sub(pr.reportedAt) < pr.interval {
+		pr.mutex.Unlock()
+		return n, err
+	}
+	pr.done = done
+	pr.reportedAt = now
+	progress := &Progress{
+		Transferred: pr.transferred,
+		Total:       pr.total,
+		Done:        done,
+	}
+	if elapsed := now.Sub(pr.startedAt).Seconds(); elapsed > 0 {
+		progress.Rate = float64(pr.transferred) / elapsed
+	}
+	pr.mutex.Unlock()
+	pr.fn(progress)
+	return n, err
+}
+
+// Close closes the body
+func (pr *progressReader) Close() error {
+	return pr.r.Close()
+}
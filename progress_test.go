@@ -0,0 +1,150 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowReader sleeps before each read
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	if len(p) > 10 {
+		p = p[:10]
+	}
+	return sr.r.Read(p)
+}
+
+func TestProgressReader(t *testing.T) {
+	assert := assert.New(t)
+
+	items := make([]*Progress, 0)
+	r := newProgressReader(io.NopCloser(&slowReader{
+		r:     strings.NewReader(strings.Repeat("a", 50)),
+		delay: 10 * time.Millisecond,
+	}), 50, 25*time.Millisecond, func(progress *Progress) {
+		items = append(items, progress)
+	})
+	buf, err := io.ReadAll(r)
+	assert.Nil(err)
+	assert.Nil(r.Close())
+	assert.Equal(50, len(buf))
+	// 按间隔回调，完成时回调一次
+	assert.True(len(items) >= 2 && len(items) < 5)
+	last := items[len(items)-1]
+	assert.True(last.Done)
+	assert.Equal(int64(50), last.Transferred)
+	assert.Equal(int64(50), last.Total)
+	assert.True(last.Rate > 0)
+	for _, item := range items[:len(items)-1] {
+		assert.False(item.Done)
+		assert.True(item.Transferred < 50)
+	}
+
+	// 未知长度
+	items = items[:0]
+	r = newProgressReader(io.NopCloser(strings.NewReader("abc")), -1, 0, func(progress *Progress) {
+		items = append(items, progress)
+	})
+	_, _ = io.ReadAll(r)
+	assert.Equal(1, len(items))
+	assert.Equal(int64(-1), items[0].Total)
+	assert.Equal(int64(3), items[0].Transferred)
+	assert.True(items[0].Done)
+}
+
+func TestRequestProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	data := bytes.Repeat([]byte("a"), 1024*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		w.Header().Set(headerContentLength, strconv.Itoa(len(buf)))
+		_, _ = w.Write(buf)
+	}))
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	})
+	var mutex sync.Mutex
+	var upload, download *Progress
+	resp, err := ins.Request(&Config{
+		URL:    "/",
+		Method: http.MethodPost,
+		Body:   data,
+		OnUploadProgress: func(progress *Progress) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			upload = progress
+		},
+		OnDownloadProgress: func(progress *Progress) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			download = progress
+		},
+		ProgressInterval: time.Millisecond,
+	})
+	assert.Nil(err)
+	assert.Equal(data, resp.Data)
+	// 请求体保留长度
+	assert.Equal(int64(len(data)), resp.Request.ContentLength)
+	mutex.Lock()
+	assert.Equal(int64(len(data)), upload.Transferred)
+	assert.Equal(int64(len(data)), upload.Total)
+	assert.True(upload.Done)
+	assert.Equal(int64(len(data)), download.Transferred)
+	assert.Equal(int64(len(data)), download.Total)
+	assert.True(download.Done)
+	mutex.Unlock()
+
+	// 流式响应在读取时回调
+	download = nil
+	resp, err = ins.Request(&Config{
+		URL:    "/",
+		Method: http.MethodPost,
+		Body:   io.NopCloser(bytes.NewReader(data)),
+		Stream: true,
+		OnDownloadProgress: func(progress *Progress) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			download = progress
+		},
+	})
+	assert.Nil(err)
+	buf, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Nil(resp.Body.Close())
+	assert.Equal(data, buf)
+	mutex.Lock()
+	assert.Equal(int64(len(data)), download.Transferred)
+	assert.True(download.Done)
+	mutex.Unlock()
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrQueueTimeout = errors.New("wait for request slot timeout")
+
+type (
+	queueWaiter struct {
+		priority int
+		seq      uint64
+		index    int
+		granted  bool
+		ready    chan struct{}
+	}
+	// queueWaiters priority queue of waiters,
+	// the higher priority first, and fifo for the same priority
+	queueWaiters []*queueWaiter
+
+	// requestQueue queue of requests waiting for the concurrency slot
+	requestQueue struct {
+		mutex   sync.Mutex
+		seq     uint64
+		waiters queueWaiters
+	}
+)
+
+func (qw queueWaiters) Len() int {
+	return len(qw)
+}
+
+func (qw queueWaiters) Less(i, j int) bool {
+	if qw[i].priority != qw[j].priority {
+		return qw[i].priority > qw[j].priority
+	}
+	return qw[i].seq < qw[j].seq
+}
+
+func (qw queueWaiters) Swap(i, j int) {
+	qw[i], qw[j] = qw[j], qw[i]
+	qw[i].index = i
+	qw[j].index = j
+}
+
+func (qw *queueWaiters) Push(x interface{}) {
+	w := x.(*queueWaiter)
+	w.index = len(*qw)
+	*qw = append(*qw, w)
+}
+
+func (qw *queueWaiters) Pop() interface{} {
+	old := *qw
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*qw = old[:n-1]
+	return w
+}
+
+// length returns the count of waiting requests
+func (q *requestQueue) length() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.waiters)
+}
+
+// acquire acquires a concurrency slot of instance,
+// it waits in the queue if the concurrency is full
+func (q *requestQueue) acquire(ins *Instance, config *Config) (uint32, error) {
+	maxConcurrency := uint32(atomic.LoadInt32(&ins.Config.MaxConcurrency))
+	q.mutex.Lock()
+	// 无等待请求且并发数未满，直接获取
+	if len(q.waiters) == 0 && atomic.LoadUint32(&ins.concurrency) < maxConcurrency {
+		value := atomic.AddUint32(&ins.concurrency, 1)
+		q.mutex.Unlock()
+		return value, nil
+	}
+	if len(q.waiters) >= ins.Config.QueueSize {
+		q.mutex.Unlock()
+		return 0, ErrTooManyRequests
+	}
+	q.seq++
+	w := &queueWaiter{
+		priority: config.Priority,
+		seq:      q.seq,
+		ready:    make(chan struct{}),
+	}
+	heap.Push(&q.waiters, w)
+	q.mutex.Unlock()
+
+	ctx := config.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var timeout <-chan time.Time
+	if ins.Config.QueueTimeout > 0 {
+		timer := time.NewTimer(ins.Config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return atomic.LoadUint32(&ins.concurrency), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+	q.mutex.Lock()
+	granted := w.granted
+	if !granted {
+		heap.Remove(&q.waiters, w.index)
+	}
+	q.mutex.Unlock()
+	// 在超时的同时已获取到slot，则释放
+	if granted {
+		q.release(ins)
+	}
+	return 0, err
+}
+
+// release releases the concurrency slot and wakes up the waiting requests
+func (q *requestQueue) release(ins *Instance) {
+	maxConcurrency := uint32(atomic.LoadInt32(&ins.Config.MaxConcurrency))
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	atomic.AddUint32(&ins.concurrency, ^uint32(0))
+	for len(q.waiters) != 0 && atomic.LoadUint32(&ins.concurrency) < maxConcurrency {
+		w := heap.Pop(&q.waiters).(*queueWaiter)
+		w.granted = true
+		atomic.AddUint32(&ins.concurrency, 1)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestQueue(t *testing.T) {
+	t.Run("wait for slot", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance(&InstanceConfig{
+			MaxConcurrency: 1,
+			QueueSize:      2,
+			Adapter: func(config *Config) (*Response, error) {
+				time.Sleep(10 * time.Millisecond)
+				return &Response{}, nil
+			},
+		})
+		wg := sync.WaitGroup{}
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := ins.Get("/")
+				assert.Nil(err)
+			}()
+		}
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(uint32(1), ins.GetConcurrency())
+		assert.Equal(2, ins.GetQueueLength())
+
+		// 队列已满
+		_, err := ins.Get("/")
+		assert.Equal(ErrTooManyRequests, err)
+		wg.Wait()
+		assert.Equal(uint32(0), ins.GetConcurrency())
+		assert.Equal(0, ins.GetQueueLength())
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		assert := assert.New(t)
+		mutex := sync.Mutex{}
+		routes := make([]string, 0)
+		ins := NewInstance(&InstanceConfig{
+			MaxConcurrency: 1,
+			QueueSize:      10,
+			Adapter: func(config *Config) (*Response, error) {
+				mutex.Lock()
+				routes = append(routes, config.Route)
+				mutex.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				return &Response{}, nil
+			},
+		})
+		wg := sync.WaitGroup{}
+		for i, route := range []string{"/first", "/low", "/high"} {
+			priority := 0
+			if route == "/high" {
+				priority = 1
+			}
+			wg.Add(1)
+			go func(route string, priority int) {
+				defer wg.Done()
+				_, err := ins.Request(&Config{
+					URL:      route,
+					Priority: priority,
+				})
+				assert.Nil(err)
+			}(route, priority)
+			time.Sleep(time.Duration(i+1) * time.Millisecond)
+		}
+		wg.Wait()
+		assert.Equal([]string{"/first", "/high", "/low"}, routes)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance(&InstanceConfig{
+			MaxConcurrency: 1,
+			QueueSize:      1,
+			QueueTimeout:   time.Millisecond,
+			Adapter: func(config *Config) (*Response, error) {
+				time.Sleep(20 * time.Millisecond)
+				return &Response{}, nil
+			},
+		})
+		go func() {
+			_, _ = ins.Get("/")
+		}()
+		time.Sleep(5 * time.Millisecond)
+		_, err := ins.Get("/")
+		assert.Equal(ErrQueueTimeout, err)
+		assert.Equal(0, ins.GetQueueLength())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = ins.GetX(ctx, "/")
+		assert.Equal(context.Canceled, err)
+	})
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+var ErrRateLimited = errors.New("request is rate limited")
+
+type (
+	// RateLimit limit of token bucket
+	RateLimit struct {
+		// Rate tokens generated per second
+		Rate float64
+		// Burst max tokens of bucket, default is 1
+		Burst int
+	}
+	// RateLimiterConfig config of rate limiter
+	RateLimiterConfig struct {
+		// Global limit for all requests of instance
+		Global *RateLimit
+		// Routes limit for the route
+		Routes map[string]*RateLimit
+		// Wait waits for the token if it's true,
+		// otherwise the request fails with ErrRateLimited
+		Wait bool
+		// DisableAdaptive disables adapting the limit by
+		// Retry-After and X-RateLimit-* response headers
+		DisableAdaptive bool
+	}
+
+	tokenBucket struct {
+		mutex  sync.Mutex
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+		// 在此时间前不可请求
+		blockedUntil time.Time
+	}
+	rateLimiter struct {
+		conf   *RateLimiterConfig
+		global *tokenBucket
+		routes map[string]*tokenBucket
+	}
+)
+
+func newTokenBucket(limit *RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refill refills the tokens of bucket, it should be called with lock
+func (tb *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(tb.last)
+	if elapsed <= 0 {
+		return
+	}
+	tb.tokens = math.Min(tb.burst, tb.tokens+elapsed.Seconds()*tb.rate)
+	tb.last = now
+}
+
+// reserve takes a token from bucket, it returns the duration to wait if no token
+func (tb *tokenBucket) reserve() time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	now := time.Now()
+	tb.refill(now)
+	if now.Before(tb.blockedUntil) {
+		return tb.blockedUntil.Sub(now)
+	}
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return 0
+	}
+	if tb.rate <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+// refund returns a token to bucket
+func (tb *tokenBucket) refund() {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.refill(time.Now())
+	tb.tokens = math.Min(tb.burst, tb.tokens+1)
+}
+
+// available returns the current tokens of bucket
+func (tb *tokenBucket) available() float64 {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.refill(time.Now())
+	return tb.tokens
+}
+
+// adapt adapts the bucket by the response headers
+func (tb *tokenBucket) adapt(status int, header http.Header) {
+	now := time.Now()
+	var until time.Time
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		until = parseRetryAfter(header.Get(headerRetryAfter), now)
+	}
+	remaining, err := strconv.ParseFloat(strings.TrimSpace(header.Get(headerRateLimitRemaining)), 64)
+	hasRemaining := err == nil
+	if hasRemaining && remaining <= 0 && until.IsZero() {
+		until = parseRateLimitReset(header.Get(headerRateLimitReset), now)
+	}
+
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.refill(now)
+	if hasRemaining && remaining < tb.tokens {
+		tb.tokens = remaining
+	}
+	if until.After(tb.blockedUntil) {
+		tb.blockedUntil = until
+	}
+}
+
+// parseRetryAfter parses the value of Retry-After, it may be seconds or http date
+func parseRetryAfter(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseRateLimitReset parses the value of X-RateLimit-Reset,
+// it may be unix timestamp or delta seconds
+func parseRateLimitReset(value string, now time.Time) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	// 大于一年的值视为unix时间戳
+	if seconds > 365*24*3600 {
+		return time.Unix(seconds, 0)
+	}
+	return now.Add(time.Duration(seconds) * time.Second)
+}
+
+func newRateLimiter(conf *RateLimiterConfig) *rateLimiter {
+	rl := &rateLimiter{
+		conf:   conf,
+		routes: make(map[string]*tokenBucket),
+	}
+	if conf.Global != nil {
+		rl.global = newTokenBucket(conf.Global)
+	}
+	for route, limit := range conf.Routes {
+		if limit != nil {
+			rl.routes[route] = newTokenBucket(limit)
+		}
+	}
+	return rl
+}
+
+// buckets returns the buckets of request
+func (rl *rateLimiter) buckets(config *Config) []*tokenBucket {
+	buckets := make([]*tokenBucket, 0, 2)
+	if tb, ok := rl.routes[config.getRoute()]; ok {
+		buckets = append(buckets, tb)
+	}
+	if rl.global != nil {
+		buckets = append(buckets, rl.global)
+	}
+	return buckets
+}
+
+// take takes tokens for the request, it waits for the token if wait is enabled.
+// The tokens which have been taken are refunded if the request fails to take all
+func (rl *rateLimiter) take(ctx context.Context, config *Config) error {
+	if rl == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	buckets := rl.buckets(config)
+	for index, tb := range buckets {
+		if err := rl.takeToken(ctx, tb); err != nil {
+			// 已获取的token归还，避免被拒绝的请求消耗其它bucket的token
+			for _, taken := range buckets[:index] {
+				taken.refund()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// takeToken takes a token from bucket
+func (rl *rateLimiter) takeToken(ctx context.Context, tb *tokenBucket) error {
+	for {
+		d := tb.reserve()
+		if d <= 0 {
+			return nil
+		}
+		if !rl.conf.Wait {
+			return ErrRateLimited
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// adapt adapts the buckets of request by response
+func (rl *rateLimiter) adapt(config *Config, resp *Response) {
+	if rl == nil || rl.conf.DisableAdaptive || resp == nil || resp.Headers == nil {
+		return
+	}
+	for _, tb := range rl.buckets(config) {
+		tb.adapt(resp.Status, resp.Headers)
+	}
+}
+
+// tokens returns the available tokens of route, global bucket is used if route is empty
+func (rl *rateLimiter) tokens(route string) (float64, bool) {
+	if rl == nil {
+		return 0, false
+	}
+	tb := rl.global
+	if route != "" {
+		tb = rl.routes[route]
+	}
+	if tb == nil {
+		return 0, false
+	}
+	return tb.available(), true
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1000, 0)
+
+	assert.True(parseRetryAfter("", now).IsZero())
+	assert.Equal(time.Unix(1010, 0), parseRetryAfter("10", now))
+	assert.Equal(time.Unix(1445412480, 0).UTC(), parseRetryAfter("Wed, 21 Oct 2015 07:28:00 GMT", now))
+
+	assert.True(parseRateLimitReset("", now).IsZero())
+	assert.Equal(time.Unix(1010, 0), parseRateLimitReset("10", now))
+	assert.Equal(time.Unix(1445412480, 0), parseRateLimitReset("1445412480", now))
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("reject", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance(&InstanceConfig{
+			RateLimiter: &RateLimiterConfig{
+				Global: &RateLimit{
+					Rate:  1,
+					Burst: 2,
+				},
+				Routes: map[string]*RateLimit{
+					"/users/:type": {
+						Rate: 1,
+					},
+				},
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				return &Response{
+					Status: 200,
+				}, nil
+			},
+		})
+		tokens, ok := ins.GetRateLimitTokens("")
+		assert.True(ok)
+		assert.Equal(float64(2), tokens)
+
+		conf := &Config{
+			Route: "/users/:type",
+			URL:   "/users/me",
+		}
+		_, err := ins.Request(conf)
+		assert.Nil(err)
+		_, err = ins.Request(&Config{
+			Route: "/users/:type",
+			URL:   "/users/me",
+		})
+		assert.Equal(ErrRateLimited, err)
+
+		_, err = ins.Get("/")
+		assert.Nil(err)
+		_, err = ins.Get("/")
+		assert.Equal(ErrRateLimited, err)
+
+		_, ok = ins.GetRateLimitTokens("/not-found")
+		assert.False(ok)
+	})
+
+	t.Run("reject refund", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance(&InstanceConfig{
+			RateLimiter: &RateLimiterConfig{
+				Global: &RateLimit{
+					Rate: 0.001,
+				},
+				Routes: map[string]*RateLimit{
+					"/users/:type": {
+						Rate:  0.001,
+						Burst: 2,
+					},
+				},
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				return &Response{
+					Status: 200,
+				}, nil
+			},
+		})
+		_, err := ins.Get("/")
+		assert.Nil(err)
+
+		// global的限制拒绝时，route的token需归还
+		for i := 0; i < 3; i++ {
+			_, err = ins.Request(&Config{
+				Route: "/users/:type",
+				URL:   "/users/me",
+			})
+			assert.Equal(ErrRateLimited, err)
+		}
+		tokens, ok := ins.GetRateLimitTokens("/users/:type")
+		assert.True(ok)
+		assert.Equal(float64(2), tokens)
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance(&InstanceConfig{
+			RateLimiter: &RateLimiterConfig{
+				Global: &RateLimit{
+					Rate: 100,
+				},
+				Wait: true,
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				return &Response{
+					Status: 200,
+				}, nil
+			},
+		})
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := ins.Get("/")
+			assert.Nil(err)
+		}
+		assert.True(time.Since(start) >= 15*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		_, err := ins.GetX(ctx, "/")
+		assert.Equal(context.DeadlineExceeded, err)
+	})
+
+	t.Run("adaptive", func(t *testing.T) {
+		assert := assert.New(t)
+		ins := NewInstance(&InstanceConfig{
+			RateLimiter: &RateLimiterConfig{
+				Global: &RateLimit{
+					Rate:  100,
+					Burst: 10,
+				},
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				headers := make(http.Header)
+				if config.Route == "/limited" {
+					headers.Set(headerRetryAfter, "10")
+					return &Response{
+						Status:  429,
+						Headers: headers,
+					}, nil
+				}
+				headers.Set(headerRateLimitRemaining, "2")
+				return &Response{
+					Status:  200,
+					Headers: headers,
+				}, nil
+			},
+		})
+		_, err := ins.Get("/")
+		assert.Nil(err)
+		tokens, _ := ins.GetRateLimitTokens("")
+		assert.True(tokens < 3)
+
+		_, err = ins.Get("/limited")
+		assert.Nil(err)
+		_, err = ins.Get("/")
+		assert.Equal(ErrRateLimited, err)
+	})
+}
@@ -15,6 +15,7 @@
 package axios
 
 import (
+	"io"
 	"net/http"
 )
 
@@ -29,9 +30,35 @@ type (
 		Request       *http.Request
 		// OriginalResponse original http response
 		OriginalResponse *http.Response
+		// Body the response body of stream request,
+		// the caller is responsible for closing it
+		Body io.ReadCloser
+		// CacheStatus cache status of response(miss, hit or revalidated),
+		// it's empty if cache is not used
+		CacheStatus string
+		// rawData the data of response before transform
+		rawData []byte
+		// rawHeaders the headers before transform, it's set only if the content is encoded
+		rawHeaders http.Header
 	}
 )
 
+// contentData returns the data of response as sent(before decompression)
+func (resp *Response) contentData() []byte {
+	if resp.rawData != nil {
+		return resp.rawData
+	}
+	return resp.Data
+}
+
+// contentHeaders returns the headers of response as sent(before decompression)
+func (resp *Response) contentHeaders() http.Header {
+	if resp.rawHeaders != nil {
+		return resp.rawHeaders
+	}
+	return resp.Headers
+}
+
 // JSON convert json data
 func (resp *Response) JSON(v interface{}) (err error) {
 	err = jsonUnmarshal(resp.Data, v)
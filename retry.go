@@ -0,0 +1,240 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+type (
+	// Backoff returns the duration to wait before the next attempt,
+	// attempt is the count of attempts which have been done(start from 1)
+	Backoff func(attempt int) time.Duration
+	// RetryCondition returns true if the request should be retried
+	RetryCondition func(config *Config, resp *Response, err error) bool
+
+	// RetryPolicy retry policy of request
+	RetryPolicy struct {
+		// MaxAttempts max attempts of request(include the first one),
+		// retry is disabled if it's lte 1
+		MaxAttempts int
+		// Backoff the backoff function, no wait if it's nil
+		Backoff Backoff
+		// Conditions the request will be retried if any of the conditions returns true,
+		// DefaultRetryCondition is used if it's empty
+		Conditions []RetryCondition
+	}
+)
+
+var retryableErrorCategories = []string{
+	ErrCategoryTimeout,
+	ErrCategoryAborted,
+	ErrCategoryRefused,
+	ErrCategoryReset,
+}
+
+var retryableStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// NewConstantBackoff creates a backoff which always returns the same duration
+func NewConstantBackoff(d time.Duration) Backoff {
+	return func(_ int) time.Duration {
+		return d
+	}
+}
+
+// NewExponentialBackoff creates an exponential backoff,
+// the duration is base * 2^(attempt-1) and not greater than maxDelay(if maxDelay gt 0),
+// it's capped at the max duration if it overflows
+func NewExponentialBackoff(base, maxDelay time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt; i++ {
+			// 避免溢出
+			if d > math.MaxInt64/2 {
+				d = math.MaxInt64
+				break
+			}
+			d *= 2
+			if maxDelay > 0 && d >= maxDelay {
+				return maxDelay
+			}
+		}
+		if maxDelay > 0 && d > maxDelay {
+			return maxDelay
+		}
+		return d
+	}
+}
+
+// NewJitterBackoff creates an exponential backoff with full jitter,
+// the duration is a random value in [0, exponential backoff duration)
+func NewJitterBackoff(base, maxDelay time.Duration) Backoff {
+	fn := NewExponentialBackoff(base, maxDelay)
+	return func(attempt int) time.Duration {
+		d := fn(attempt)
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d)))
+	}
+}
+
+// RetryOnErrorCategories returns a retry condition which retries the request
+// when the category of error is one of the categories
+func RetryOnErrorCategories(categories ...string) RetryCondition {
+	return func(_ *Config, _ *Response, err error) bool {
+		if err == nil {
+			return false
+		}
+		category := GetInternalErrorCategory(err)
+		if category == "" {
+			return false
+		}
+		for _, value := range categories {
+			if value == category {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnStatus returns a retry condition which retries the request
+// when the status of response is one of the statuses
+func RetryOnStatus(statuses ...int) RetryCondition {
+	return func(_ *Config, resp *Response, err error) bool {
+		if resp == nil {
+			return false
+		}
+		for _, value := range statuses {
+			if value == resp.Status {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DefaultRetryCondition retries the request when it's failed of timeout, aborted, refused or reset,
+// or the status of response is 429, 502, 503 or 504
+func DefaultRetryCondition(config *Config, resp *Response, err error) bool {
+	// 由调用方主动取消的请求不重试(重试判断时context为调用方的原始context)
+	if config.Context != nil && config.Context.Err() == context.Canceled {
+		return false
+	}
+	return RetryOnErrorCategories(retryableErrorCategories...)(config, resp, err) ||
+		RetryOnStatus(retryableStatuses...)(config, resp, err)
+}
+
+// enabled returns true if the policy allows to retry
+func (rp *RetryPolicy) enabled() bool {
+	return rp != nil && rp.MaxAttempts > 1
+}
+
+// shouldRetry returns true if the request should be retried
+func (rp *RetryPolicy) shouldRetry(config *Config, resp *Response, err error) bool {
+	if !rp.enabled() || config.Attempts >= rp.MaxAttempts {
+		return false
+	}
+	conditions := rp.Conditions
+	if len(conditions) == 0 {
+		conditions = []RetryCondition{
+			DefaultRetryCondition,
+		}
+	}
+	for _, fn := range conditions {
+		if fn(config, resp, err) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait waits for the backoff duration of the attempt,
+// it returns the error of context if the context is done
+func (rp *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	if rp.Backoff == nil {
+		return nil
+	}
+	d := rp.Backoff(attempt)
+	if d <= 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// readSeekerWithoutClose hides the Close of body, so the body is not closed
+// by the transport between attempts
+type readSeekerWithoutClose struct {
+	io.ReadSeeker
+}
+
+// newBodyRewinder makes the body of config could be read again, it returns a function
+// to rewind the body before each attempt and a function to close the body after all attempts.
+// The rewind is nil if the body is an io.Reader which can't seek, it's not buffered
+// into memory(e.g. large stream) and the request can't be retried.
+func (conf *Config) newBodyRewinder() (rewind func() error, release func(), err error) {
+	rewind = func() error {
+		return nil
+	}
+	release = func() {}
+	r, ok := conf.Body.(io.Reader)
+	if !ok || !isNeedToTransformRequestBody(conf.Method) {
+		return
+	}
+	seeker, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, release, nil
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 可关闭的body(如os.File)在所有请求完成后才关闭
+	if closer, ok := r.(io.Closer); ok {
+		conf.Body = &readSeekerWithoutClose{
+			ReadSeeker: seeker,
+		}
+		release = func() {
+			conf.Body = r
+			_ = closer.Close()
+		}
+	}
+	rewind = func() error {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+	return rewind, release, nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRefusedError() error {
+	return &url.Error{
+		Op:  "Get",
+		URL: "http://127.0.0.1/",
+		Err: &net.OpError{
+			Op: "dial",
+			Err: &os.SyscallError{
+				Err: syscall.ECONNREFUSED,
+			},
+		},
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	fn := NewConstantBackoff(time.Second)
+	assert.Equal(time.Second, fn(1))
+	assert.Equal(time.Second, fn(10))
+
+	fn = NewExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	assert.Equal(10*time.Millisecond, fn(1))
+	assert.Equal(20*time.Millisecond, fn(2))
+	assert.Equal(40*time.Millisecond, fn(3))
+	assert.Equal(50*time.Millisecond, fn(4))
+	assert.Equal(50*time.Millisecond, fn(100))
+
+	// 无最大值时溢出则使用最大的时长
+	fn = NewExponentialBackoff(time.Second, 0)
+	assert.Equal(time.Duration(1<<33)*time.Second, fn(34))
+	assert.Equal(time.Duration(math.MaxInt64), fn(35))
+	assert.Equal(time.Duration(math.MaxInt64), fn(70))
+	assert.Equal(time.Duration(math.MaxInt64), fn(1000))
+	assert.True(NewJitterBackoff(time.Second, 0)(70) >= 0)
+
+	fn = NewJitterBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for i := 1; i < 10; i++ {
+		d := fn(i)
+		assert.True(d >= 0 && d < 50*time.Millisecond)
+	}
+}
+
+func TestRetryCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	conf := &Config{}
+	assert.True(DefaultRetryCondition(conf, nil, newRefusedError()))
+	assert.False(DefaultRetryCondition(conf, nil, errors.New("custom error")))
+	assert.True(DefaultRetryCondition(conf, &Response{
+		Status: 503,
+	}, nil))
+	assert.False(DefaultRetryCondition(conf, &Response{
+		Status: 500,
+	}, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(DefaultRetryCondition(&Config{
+		Context: ctx,
+	}, nil, newRefusedError()))
+
+	assert.True(RetryOnStatus(500)(conf, &Response{
+		Status: 500,
+	}, nil))
+	assert.False(RetryOnErrorCategories(ErrCategoryTimeout)(conf, nil, newRefusedError()))
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("retry until success", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		interceptorCount := 0
+		var doneConfig *Config
+		ins := NewInstance(&InstanceConfig{
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     NewConstantBackoff(time.Millisecond),
+			},
+			RequestInterceptors: []RequestInterceptor{
+				func(config *Config) error {
+					interceptorCount++
+					return nil
+				},
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				buf, _ := io.ReadAll(config.Request.Body)
+				assert.Equal("abc", string(buf))
+				if count < 3 {
+					return nil, newRefusedError()
+				}
+				return &Response{
+					Status: 200,
+					Data:   []byte("ok"),
+				}, nil
+			},
+			OnDone: func(config *Config, resp *Response, err error) {
+				doneConfig = config
+			},
+		})
+		resp, err := ins.Post("/", strings.NewReader("abc"))
+		assert.Nil(err)
+		assert.Equal("ok", string(resp.Data))
+		assert.Equal(3, count)
+		assert.Equal(3, interceptorCount)
+		assert.Equal(3, doneConfig.Attempts)
+		assert.Equal(3, GetStats(doneConfig, err).Attempts)
+	})
+
+	t.Run("retry seekable body", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Retry: &RetryPolicy{
+				MaxAttempts: 2,
+				Conditions: []RetryCondition{
+					RetryOnStatus(500),
+				},
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				buf, _ := io.ReadAll(config.Request.Body)
+				assert.Equal("abc", string(buf))
+				return &Response{
+					Status: 500,
+				}, nil
+			},
+		})
+		resp, err := ins.Post("/", bytes.NewReader([]byte("abc")))
+		assert.Nil(err)
+		assert.Equal(500, resp.Status)
+		assert.Equal(2, count)
+	})
+
+	t.Run("retry upload", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Retry: &RetryPolicy{
+				MaxAttempts: 2,
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				buf, _ := io.ReadAll(config.Request.Body)
+				assert.Contains(string(buf), "tree")
+				return &Response{
+					Status: 503,
+				}, nil
+			},
+		})
+		file := NewMultipartFile()
+		assert.Nil(file.AddFields(map[string]string{
+			"name": "tree",
+		}))
+		resp, err := ins.Upload("/", file)
+		assert.Nil(err)
+		assert.Equal(503, resp.Status)
+		assert.Equal(2, count)
+		// 请求完成后依然可输出请求数据
+		assert.Contains(resp.Config.CURL(), "tree")
+	})
+
+	t.Run("retry with timeout", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Timeout: time.Second,
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				return &Response{
+					Status: 503,
+				}, nil
+			},
+		})
+		resp, err := ins.Get("/")
+		assert.Nil(err)
+		assert.Equal(503, resp.Status)
+		assert.Equal(3, count)
+	})
+
+	t.Run("retry file body", func(t *testing.T) {
+		assert := assert.New(t)
+		file := filepath.Join(t.TempDir(), "body.txt")
+		assert.Nil(os.WriteFile(file, []byte("abc"), 0600))
+		f, err := os.Open(file)
+		assert.Nil(err)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, _ := io.ReadAll(r.Body)
+			assert.Equal("abc", string(buf))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			BaseURL: server.URL,
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+			},
+			RequestInterceptors: []RequestInterceptor{
+				func(config *Config) error {
+					count++
+					return nil
+				},
+			},
+		})
+		resp, err := ins.Post("/", f)
+		assert.Nil(err)
+		assert.Equal(http.StatusServiceUnavailable, resp.Status)
+		assert.Equal(3, count)
+		// 所有请求完成后关闭
+		_, err = f.Read(make([]byte, 1))
+		assert.Equal(os.ErrClosed, errors.Unwrap(err))
+	})
+
+	t.Run("no retry for stream body", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ins := NewInstance(&InstanceConfig{
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				return &Response{
+					Status: 503,
+				}, nil
+			},
+		})
+		r := io.MultiReader(strings.NewReader("abc"))
+		resp, err := ins.Post("/", r)
+		assert.Nil(err)
+		assert.Equal(503, resp.Status)
+		assert.Equal(1, count)
+		// 数据未被读取至内存
+		buf, _ := io.ReadAll(r)
+		assert.Equal("abc", string(buf))
+	})
+
+	t.Run("no retry", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		customErr := errors.New("custom error")
+		ins := NewInstance(&InstanceConfig{
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+			},
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				return nil, customErr
+			},
+		})
+		_, err := ins.Get("/")
+		assert.Equal(customErr, err)
+		assert.Equal(1, count)
+	})
+
+	t.Run("context canceled while waiting", func(t *testing.T) {
+		assert := assert.New(t)
+		count := 0
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		ins := NewInstance(&InstanceConfig{
+			Adapter: func(config *Config) (*Response, error) {
+				count++
+				return nil, newRefusedError()
+			},
+		})
+		conf := &Config{
+			URL:     "/",
+			Context: ctx,
+			Retry: &RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     NewConstantBackoff(time.Second),
+			},
+		}
+		_, err := ins.Request(conf)
+		assert.Equal(ErrCategoryRefused, GetInternalErrorCategory(err))
+		assert.Equal(1, count)
+		assert.Equal(1, conf.Attempts)
+	})
+}
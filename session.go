@@ -0,0 +1,470 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// CookieEntry the cookie stored in cookie jar
+	CookieEntry struct {
+		Name     string        `json:"name"`
+		Value    string        `json:"value"`
+		Domain   string        `json:"domain"`
+		Path     string        `json:"path"`
+		Secure   bool          `json:"secure,omitempty"`
+		HttpOnly bool          `json:"httpOnly,omitempty"`
+		SameSite http.SameSite `json:"sameSite,omitempty"`
+		// HostOnly the cookie is only sent to the host which sets it
+		HostOnly bool `json:"hostOnly,omitempty"`
+		// Expires the expired time of cookie, it's zero for session cookie
+		Expires  time.Time `json:"expires"`
+		Creation time.Time `json:"creation"`
+	}
+
+	// CookieJar in-memory cookie jar which implements http.CookieJar,
+	// the public suffix list is not used, so the cookie of any domain is accepted
+	CookieJar struct {
+		mutex sync.Mutex
+		// id the unique id of jar, it's used to isolate the response cache of sessions
+		id uint64
+		// entries domain -> id -> cookie
+		entries map[string]map[string]*CookieEntry
+		now     func() time.Time
+	}
+)
+
+var cookieJarID uint64
+
+// NewCookieJar creates an in-memory cookie jar
+func NewCookieJar() *CookieJar {
+	return &CookieJar{
+		id:      atomic.AddUint64(&cookieJarID, 1),
+		entries: make(map[string]map[string]*CookieEntry),
+		now:     time.Now,
+	}
+}
+
+func (e *CookieEntry) id() string {
+	return e.Name + ";" + e.Domain + ";" + e.Path
+}
+
+func (e *CookieEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// cookie converts the entry to http cookie
+func (e *CookieEntry) cookie() *http.Cookie {
+	c := &http.Cookie{
+		Name:     e.Name,
+		Value:    e.Value,
+		Path:     e.Path,
+		Secure:   e.Secure,
+		HttpOnly: e.HttpOnly,
+		SameSite: e.SameSite,
+		Expires:  e.Expires,
+	}
+	if !e.HostOnly {
+		c.Domain = e.Domain
+	}
+	return c
+}
+
+// canonicalCookieHost returns the lower case host without port
+func canonicalCookieHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	return strings.ToLower(host)
+}
+
+// cookieDomainMatch returns true if the host matches the domain(rfc 6265 5.1.3)
+func cookieDomainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+// cookiePathMatch returns true if the request path matches the cookie path(rfc 6265 5.1.4)
+func cookiePathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath returns the default path of cookie(rfc 6265 5.1.4)
+func defaultCookiePath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(requestPath, "/")
+	if i == 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// SetCookies handles the receipt of the cookies in a reply for the given URL
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host := canonicalCookieHost(u.Host)
+	if host == "" {
+		return
+	}
+	now := j.now()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, c := range cookies {
+		e := &CookieEntry{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+			Creation: now,
+		}
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		if domain == "" {
+			e.Domain = host
+			e.HostOnly = true
+		} else {
+			// 非当前host或其父域名的cookie忽略
+			if !cookieDomainMatch(host, domain) {
+				continue
+			}
+			e.Domain = domain
+		}
+		if e.Path == "" || e.Path[0] != '/' {
+			e.Path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case c.MaxAge < 0:
+			e.Expires = now
+		case c.MaxAge > 0:
+			e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			e.Expires = c.Expires
+		}
+		j.set(e, now)
+	}
+}
+
+// set stores the entry, the expired entry is removed
+func (j *CookieJar) set(e *CookieEntry, now time.Time) {
+	submap := j.entries[e.Domain]
+	id := e.id()
+	if e.expired(now) {
+		delete(submap, id)
+		if len(submap) == 0 {
+			delete(j.entries, e.Domain)
+		}
+		return
+	}
+	if submap == nil {
+		submap = make(map[string]*CookieEntry)
+		j.entries[e.Domain] = submap
+	}
+	// 更新时保留创建时间
+	if old, ok := submap[id]; ok {
+		e.Creation = old.Creation
+	}
+	submap[id] = e
+}
+
+// Cookies returns the cookies to send in a request for the given URL
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host := canonicalCookieHost(u.Host)
+	if host == "" {
+		return nil
+	}
+	requestPath := u.Path
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	https := u.Scheme == "https"
+	now := j.now()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	selected := make([]*CookieEntry, 0)
+	// 当前host以及各级父域名
+	domain := host
+	for {
+		for id, e := range j.entries[domain] {
+			if e.expired(now) {
+				delete(j.entries[domain], id)
+				continue
+			}
+			if (e.HostOnly && e.Domain != host) ||
+				(e.Secure && !https) ||
+				!cookiePathMatch(requestPath, e.Path) {
+				continue
+			}
+			selected = append(selected, e)
+		}
+		i := strings.Index(domain, ".")
+		if i == -1 || net.ParseIP(host) != nil {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	// path更长的优先，相同则创建时间早的优先，再按名称排序
+	sort.Slice(selected, func(i, j int) bool {
+		a := selected[i]
+		b := selected[j]
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		if !a.Creation.Equal(b.Creation) {
+			return a.Creation.Before(b.Creation)
+		}
+		return a.Name < b.Name
+	})
+	cookies := make([]*http.Cookie, len(selected))
+	for index, e := range selected {
+		cookies[index] = &http.Cookie{
+			Name:  e.Name,
+			Value: e.Value,
+		}
+	}
+	return cookies
+}
+
+// Domains returns the domains which have cookies
+func (j *CookieJar) Domains() []string {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	domains := make([]string, 0, len(j.entries))
+	for domain := range j.entries {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// DomainCookies returns the cookies stored for the domain, all cookies are
+// returned if the domain is empty
+func (j *CookieJar) DomainCookies(domain string) []*http.Cookie {
+	entries := j.domainEntries(canonicalCookieHost(domain))
+	cookies := make([]*http.Cookie, len(entries))
+	for index, e := range entries {
+		cookies[index] = e.cookie()
+	}
+	return cookies
+}
+
+// domainEntries returns the unexpired entries of domain(all domains if empty),
+// which are sorted by domain, path and name
+func (j *CookieJar) domainEntries(domain string) []*CookieEntry {
+	now := j.now()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	entries := make([]*CookieEntry, 0)
+	for key, submap := range j.entries {
+		if domain != "" && key != domain {
+			continue
+		}
+		for _, e := range submap {
+			if e.expired(now) {
+				continue
+			}
+			entry := *e
+			entries = append(entries, &entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a := entries[i]
+		b := entries[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Name < b.Name
+	})
+	return entries
+}
+
+// Clear removes the cookies of the domain
+func (j *CookieJar) Clear(domain string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	delete(j.entries, canonicalCookieHost(domain))
+}
+
+// ClearAll removes all cookies
+func (j *CookieJar) ClearAll() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.entries = make(map[string]map[string]*CookieEntry)
+}
+
+// Save writes the unexpired cookies(including session cookies) to writer as json
+func (j *CookieJar) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(j.domainEntries(""))
+}
+
+// Load reads the cookies from reader, they are merged into the jar
+func (j *CookieJar) Load(r io.Reader) error {
+	entries := make([]*CookieEntry, 0)
+	err := json.NewDecoder(r).Decode(&entries)
+	if err != nil {
+		return err
+	}
+	now := j.now()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, e := range entries {
+		e.Domain = canonicalCookieHost(e.Domain)
+		if e.Name == "" || e.Domain == "" {
+			continue
+		}
+		if e.Path == "" {
+			e.Path = "/"
+		}
+		j.set(e, now)
+	}
+	return nil
+}
+
+// SaveFile writes the cookies to file, the file is only readable by the owner
+func (j *CookieJar) SaveFile(file string) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = j.Save(f)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// LoadFile reads the cookies from file, it's ignored if the file does not exist
+func (j *CookieJar) LoadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return j.Load(f)
+}
+
+// newSessionAdapter creates an adapter which sends and stores the cookies of jar,
+// the jar is set to http client for default adapter to handle the redirects
+func newSessionAdapter(jar *CookieJar, adapter Adapter) Adapter {
+	return func(config *Config) (*Response, error) {
+		// 默认adapter由client处理cookie(包括重定向)，
+		// 如果client已设置jar则使用client的
+		if config.Adapter == nil {
+			client := config.Client
+			if client == nil {
+				client = http.DefaultClient
+			}
+			if client.Jar == nil {
+				c := *client
+				c.Jar = jar
+				config.Client = &c
+			}
+			return adapter(config)
+		}
+		req := config.Request
+		for _, c := range jar.Cookies(req.URL) {
+			req.AddCookie(c)
+		}
+		resp, err := adapter(config)
+		if err != nil {
+			return nil, err
+		}
+		cookies := (&http.Response{
+			Header: resp.Headers,
+		}).Cookies()
+		if len(cookies) != 0 {
+			jar.SetCookies(req.URL, cookies)
+		}
+		return resp, nil
+	}
+}
+
+// cacheSession returns the session of response cache, the response of session
+// may be different by cookies, so the cache is isolated by session
+func (j *CookieJar) cacheSession() string {
+	if j == nil {
+		return ""
+	}
+	return "session:" + strconv.FormatUint(j.id, 10)
+}
+
+// CookieJar returns the cookie jar of instance, it's nil if session is not enabled
+func (ins *Instance) CookieJar() *CookieJar {
+	return ins.cookieJar
+}
+
+// Fork creates a sub session which shares the config of instance,
+// but has its own cookie jar. The concurrency, queue, rate limiter,
+// circuit breaker, bandwidth and concurrency limiter are shared with instance,
+// so the requests of fork count against the limits of instance
+func (ins *Instance) Fork() *Instance {
+	conf := *ins.Config
+	conf.EnableSession = true
+	// 避免append时影响原有实例
+	conf.Headers = conf.Headers.Clone()
+	conf.TransformRequest = conf.TransformRequest[:len(conf.TransformRequest):len(conf.TransformRequest)]
+	conf.TransformResponse = conf.TransformResponse[:len(conf.TransformResponse):len(conf.TransformResponse)]
+	conf.RequestInterceptors = conf.RequestInterceptors[:len(conf.RequestInterceptors):len(conf.RequestInterceptors)]
+	conf.ResponseInterceptors = conf.ResponseInterceptors[:len(conf.ResponseInterceptors):len(conf.ResponseInterceptors)]
+	conf.onErrors = conf.onErrors[:len(conf.onErrors):len(conf.onErrors)]
+	conf.onDones = conf.onDones[:len(conf.onDones):len(conf.onDones)]
+	conf.onBeforeNewRequests = conf.onBeforeNewRequests[:len(conf.onBeforeNewRequests):len(conf.onBeforeNewRequests)]
+	conf.onCircuitStateChanges = conf.onCircuitStateChanges[:len(conf.onCircuitStateChanges):len(conf.onCircuitStateChanges)]
+	conf.adapterWrappers = conf.adapterWrappers[:len(conf.adapterWrappers):len(conf.adapterWrappers)]
+	return &Instance{
+		Config:             &conf,
+		parent:             ins.root(),
+		circuitBreaker:     ins.circuitBreaker,
+		rateLimiter:        ins.rateLimiter,
+		cookieJar:          NewCookieJar(),
+		bandwidth:          ins.bandwidth,
+		concurrencyLimiter: ins.concurrencyLimiter,
+	}
+}
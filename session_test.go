@@ -0,0 +1,370 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cookieNames(cookies []*http.Cookie) []string {
+	names := make([]string, len(cookies))
+	for index, c := range cookies {
+		names[index] = c.Name + "=" + c.Value
+	}
+	return names
+}
+
+func TestCookieJar(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1700000000, 0)
+	jar := NewCookieJar()
+	jar.now = func() time.Time {
+		return now
+	}
+	u, _ := url.Parse("https://www.example.com/users/me")
+	jar.SetCookies(u, []*http.Cookie{
+		{
+			Name:  "host",
+			Value: "1",
+		},
+		{
+			Name:   "domain",
+			Value:  "2",
+			Domain: ".example.com",
+			Path:   "/",
+		},
+		{
+			Name:   "secure",
+			Value:  "3",
+			Path:   "/",
+			Secure: true,
+		},
+		{
+			Name:   "expired",
+			Value:  "4",
+			MaxAge: 1,
+		},
+		// 其它域名的cookie忽略
+		{
+			Name:   "other",
+			Value:  "5",
+			Domain: "other.com",
+		},
+		// 无public suffix list，可设置顶级域名的cookie
+		{
+			Name:   "tld",
+			Value:  "6",
+			Domain: "com",
+			Path:   "/",
+		},
+	})
+	assert.Equal([]string{
+		"com",
+		"example.com",
+		"www.example.com",
+	}, jar.Domains())
+
+	assert.Equal([]string{
+		"expired=4",
+		"host=1",
+		"domain=2",
+		"secure=3",
+		"tld=6",
+	}, cookieNames(jar.Cookies(u)))
+
+	// path不匹配、非https以及host only
+	u, _ = url.Parse("http://api.example.com/")
+	assert.Equal([]string{
+		"domain=2",
+		"tld=6",
+	}, cookieNames(jar.Cookies(u)))
+
+	// 过期
+	now = now.Add(time.Second)
+	u, _ = url.Parse("https://www.example.com/users/1")
+	assert.Equal([]string{
+		"host=1",
+		"domain=2",
+		"secure=3",
+		"tld=6",
+	}, cookieNames(jar.Cookies(u)))
+
+	// 删除
+	jar.SetCookies(u, []*http.Cookie{
+		{
+			Name:   "host",
+			MaxAge: -1,
+		},
+	})
+	cookies := jar.DomainCookies("www.example.com")
+	assert.Equal(1, len(cookies))
+	assert.Equal("secure", cookies[0].Name)
+	assert.Equal("", cookies[0].Domain)
+	assert.True(cookies[0].Secure)
+	cookies = jar.DomainCookies("Example.com")
+	assert.Equal(1, len(cookies))
+	assert.Equal("example.com", cookies[0].Domain)
+
+	jar.Clear("com")
+	assert.Equal([]string{
+		"example.com",
+		"www.example.com",
+	}, jar.Domains())
+	jar.ClearAll()
+	assert.Equal([]string{}, jar.Domains())
+
+	// ip不匹配子域名
+	u, _ = url.Parse("http://127.0.0.1:3000/")
+	jar.SetCookies(u, []*http.Cookie{
+		{
+			Name:  "ip",
+			Value: "1",
+		},
+	})
+	assert.Equal([]string{
+		"ip=1",
+	}, cookieNames(jar.Cookies(u)))
+	u, _ = url.Parse("ftp://127.0.0.1/")
+	assert.Nil(jar.Cookies(u))
+}
+
+func TestCookieJarPersist(t *testing.T) {
+	assert := assert.New(t)
+
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://aslant.site/")
+	jar.SetCookies(u, []*http.Cookie{
+		{
+			Name:  "session",
+			Value: "abc",
+		},
+		{
+			Name:    "remember",
+			Value:   "1",
+			Expires: time.Now().Add(time.Hour),
+		},
+	})
+	file := filepath.Join(t.TempDir(), "cookies.json")
+	assert.Nil(jar.SaveFile(file))
+
+	newJar := NewCookieJar()
+	assert.Nil(newJar.LoadFile(file))
+	buf := new(bytes.Buffer)
+	assert.Nil(jar.Save(buf))
+	newBuf := new(bytes.Buffer)
+	assert.Nil(newJar.Save(newBuf))
+	assert.Equal(buf.String(), newBuf.String())
+	assert.Equal([]string{
+		"remember=1",
+		"session=abc",
+	}, cookieNames(newJar.Cookies(u)))
+
+	// 文件不存在
+	assert.Nil(NewCookieJar().LoadFile(filepath.Join(t.TempDir(), "none.json")))
+	assert.NotNil(NewCookieJar().Load(bytes.NewBufferString("{")))
+}
+
+func TestSession(t *testing.T) {
+	assert := assert.New(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{
+			Name:  "session",
+			Value: r.URL.Query().Get("name"),
+			Path:  "/",
+		})
+		// 重定向时设置的cookie也需要保存
+		http.Redirect(w, r, "/me", http.StatusFound)
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("session")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(c.Value))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL:       server.URL,
+		EnableSession: true,
+	})
+	resp, err := ins.Get("/login", url.Values{
+		"name": []string{"tree"},
+	})
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.Status)
+	assert.Equal("tree", string(resp.Data))
+	resp, err = ins.Get("/me")
+	assert.Nil(err)
+	assert.Equal("tree", string(resp.Data))
+	assert.Equal(1, len(ins.CookieJar().DomainCookies("127.0.0.1")))
+
+	// fork的session cookie独立
+	sub := ins.Fork()
+	assert.Equal(server.URL, sub.Config.BaseURL)
+	resp, err = sub.Get("/me")
+	assert.Nil(err)
+	assert.Equal(http.StatusUnauthorized, resp.Status)
+	_, err = sub.Get("/login", url.Values{
+		"name": []string{"xie"},
+	})
+	assert.Nil(err)
+	resp, _ = ins.Get("/me")
+	assert.Equal("tree", string(resp.Data))
+	resp, _ = sub.Get("/me")
+	assert.Equal("xie", string(resp.Data))
+
+	// 未启用session
+	assert.Nil(NewInstance(nil).CookieJar())
+	resp, err = NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	}).Get("/me")
+	assert.Nil(err)
+	assert.Equal(http.StatusUnauthorized, resp.Status)
+}
+
+func TestSessionCustomAdapter(t *testing.T) {
+	assert := assert.New(t)
+
+	cookieHeaders := make([]string, 0)
+	ins := NewInstance(&InstanceConfig{
+		BaseURL:       "https://aslant.site",
+		EnableSession: true,
+		Adapter: func(config *Config) (*Response, error) {
+			cookieHeaders = append(cookieHeaders, config.Request.Header.Get("Cookie"))
+			headers := http.Header{}
+			headers.Add("Set-Cookie", "id=1; Path=/")
+			return &Response{
+				Status:  http.StatusOK,
+				Headers: headers,
+			}, nil
+		},
+	})
+	_, err := ins.Get("/")
+	assert.Nil(err)
+	_, err = ins.Get("/users")
+	assert.Nil(err)
+	assert.Equal([]string{
+		"",
+		"id=1",
+	}, cookieHeaders)
+}
+
+func TestSessionCache(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{
+				Name:  "session",
+				Value: r.URL.Query().Get("name"),
+				Path:  "/",
+			})
+			return
+		}
+		c, err := r.Cookie("session")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set(headerCacheControl, "max-age=60")
+		_, _ = w.Write([]byte(c.Value))
+	}))
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL:       server.URL,
+		EnableSession: true,
+		Cache:         NewLRUCache(10),
+	})
+	sub := ins.Fork()
+	_, err := ins.Get("/login", url.Values{
+		"name": []string{"tree"},
+	})
+	assert.Nil(err)
+	_, err = sub.Get("/login", url.Values{
+		"name": []string{"xie"},
+	})
+	assert.Nil(err)
+
+	resp, err := ins.Get("/me")
+	assert.Nil(err)
+	assert.Equal("tree", string(resp.Data))
+	resp, err = ins.Get("/me")
+	assert.Nil(err)
+	assert.Equal(CacheHit, resp.CacheStatus)
+	assert.Equal("tree", string(resp.Data))
+
+	// fork使用相同的cache，但不会获取其它session的缓存
+	resp, err = sub.Get("/me")
+	assert.Nil(err)
+	assert.Equal(CacheMiss, resp.CacheStatus)
+	assert.Equal("xie", string(resp.Data))
+	resp, err = sub.Get("/me")
+	assert.Nil(err)
+	assert.Equal(CacheHit, resp.CacheStatus)
+	assert.Equal("xie", string(resp.Data))
+}
+
+func TestSessionForkLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	ins := NewInstance(&InstanceConfig{
+		EnableSession: true,
+		RateLimiter: &RateLimiterConfig{
+			Global: &RateLimit{
+				Rate:  0.001,
+				Burst: 1,
+			},
+		},
+		Adapter: func(config *Config) (*Response, error) {
+			return &Response{
+				Status: http.StatusOK,
+			}, nil
+		},
+	})
+	sub := ins.Fork()
+	other := sub.Fork()
+
+	// fork的请求与原实例共享限流
+	_, err := ins.Get("/")
+	assert.Nil(err)
+	_, err = sub.Get("/")
+	assert.Equal(ErrRateLimited, err)
+	_, err = other.Get("/")
+	assert.Equal(ErrRateLimited, err)
+
+	// 共享最大并发数
+	sub.SetMaxConcurrency(-1)
+	_, err = ins.Get("/")
+	assert.Equal(ErrRequestIsForbidden, err)
+	assert.Equal(int32(-1), ins.Config.MaxConcurrency)
+	ins.SetMaxConcurrency(0)
+	assert.Equal(uint32(0), other.GetConcurrency())
+	assert.NotEqual(ins.CookieJar(), sub.CookieJar())
+}
@@ -0,0 +1,314 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+
+	// SigV4UnsignedPayload the payload hash of unsigned payload
+	SigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	headerAmzDate          = "X-Amz-Date"
+	headerAmzContentSha256 = "X-Amz-Content-Sha256"
+	headerAmzSecurityToken = "X-Amz-Security-Token"
+)
+
+type (
+	// SigV4Config config of aws signature version 4
+	SigV4Config struct {
+		// AccessKeyID access key id
+		AccessKeyID string
+		// SecretAccessKey secret access key
+		SecretAccessKey string
+		// SessionToken session token of temporary credentials
+		SessionToken string
+		// Region region of service, e.g. us-east-1
+		Region string
+		// Service name of service, e.g. s3
+		Service string
+		// UnsignedPayload uses UNSIGNED-PAYLOAD instead of the hash of body,
+		// the body which can't be read again(e.g. io.Reader) is always unsigned
+		UnsignedPayload bool
+		// DisableURIPathEscaping escapes the path only once, it should be true for s3
+		DisableURIPathEscaping bool
+	}
+
+	// SigV4Signer aws signature version 4 signer
+	SigV4Signer struct {
+		config SigV4Config
+		now    func() time.Time
+	}
+)
+
+// NewSigV4Signer creates a aws signature version 4 signer
+func NewSigV4Signer(config SigV4Config) *SigV4Signer {
+	return &SigV4Signer{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sigV4Escape escapes the value as rfc3986, only unreserved characters are kept
+func sigV4Escape(value string, keepSlash bool) string {
+	builder := strings.Builder{}
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || (keepSlash && b == '/') {
+			builder.WriteByte(b)
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%%%02X", b))
+	}
+	return builder.String()
+}
+
+func (s *SigV4Signer) canonicalURI(u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	uri := sigV4Escape(path, true)
+	// 除s3外的服务需要编码两次
+	if !s.config.DisableURIPathEscaping {
+		uri = sigV4Escape(uri, true)
+	}
+	return uri
+}
+
+func canonicalQuery(query url.Values) string {
+	items := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			items = append(items, [2]string{
+				sigV4Escape(key, false),
+				sigV4Escape(value, false),
+			})
+		}
+	}
+	// 先按编码后的key排序，再按value排序(不能按key=value排序，如a1与a)
+	sort.Slice(items, func(i, j int) bool {
+		if items[i][0] != items[j][0] {
+			return items[i][0] < items[j][0]
+		}
+		return items[i][1] < items[j][1]
+	})
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = item[0] + "=" + item[1]
+	}
+	return strings.Join(result, "&")
+}
+
+// isSigV4SignedHeader returns true if the header should be signed
+func isSigV4SignedHeader(name string) bool {
+	switch name {
+	case "host", "content-type", "content-md5":
+		return true
+	}
+	return strings.HasPrefix(name, "x-amz-")
+}
+
+// canonicalHeaders returns the canonical headers and signed headers
+func canonicalHeaders(host string, header http.Header) (string, string) {
+	values := map[string]string{
+		"host": host,
+	}
+	for key, items := range header {
+		name := strings.ToLower(key)
+		if !isSigV4SignedHeader(name) {
+			continue
+		}
+		trimmed := make([]string, len(items))
+		for i, item := range items {
+			trimmed[i] = strings.Join(strings.Fields(item), " ")
+		}
+		values[name] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	builder := strings.Builder{}
+	for _, name := range names {
+		builder.WriteString(name + ":" + values[name] + "\n")
+	}
+	return builder.String(), strings.Join(names, ";")
+}
+
+func (s *SigV4Signer) scope(t time.Time) string {
+	return strings.Join([]string{
+		t.Format(sigV4DateFormat),
+		s.config.Region,
+		s.config.Service,
+		"aws4_request",
+	}, "/")
+}
+
+// signature calculates the signature of canonical request
+func (s *SigV4Signer) signature(canonicalRequest string, t time.Time) string {
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		t.Format(sigV4TimeFormat),
+		s.scope(t),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), t.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, s.config.Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func getRequestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// sign signs the request with the payload hash, the signed headers are
+// host, content-type, content-md5 and x-amz-*
+func (s *SigV4Signer) sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	req.Header.Set(headerAmzDate, t.Format(sigV4TimeFormat))
+	if s.config.SessionToken != "" {
+		req.Header.Set(headerAmzSecurityToken, s.config.SessionToken)
+	}
+	headers, signedHeaders := canonicalHeaders(getRequestHost(req), req.Header)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set(headerAuthorization, fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm,
+		s.config.AccessKeyID,
+		s.scope(t),
+		signedHeaders,
+		s.signature(canonicalRequest, t),
+	))
+}
+
+// payloadHash returns the sha256 hash of request body
+func (s *SigV4Signer) payloadHash(req *http.Request) (string, error) {
+	if value := req.Header.Get(headerAmzContentSha256); value != "" {
+		return value, nil
+	}
+	if s.config.UnsignedPayload {
+		return SigV4UnsignedPayload, nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return sha256Hex(nil), nil
+	}
+	// 无法重复读取的body不签名
+	if req.GetBody == nil {
+		return SigV4UnsignedPayload, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, body)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RequestInterceptor signs the request, it sets the Authorization,
+// X-Amz-Date and X-Amz-Content-Sha256 headers
+func (s *SigV4Signer) RequestInterceptor(config *Config) error {
+	req := config.Request
+	payloadHash, err := s.payloadHash(req)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerAmzContentSha256, payloadHash)
+	s.sign(req, payloadHash, s.now())
+	return nil
+}
+
+// Attach appends the request interceptor of signer to instance config
+func (s *SigV4Signer) Attach(conf *InstanceConfig) {
+	conf.RequestInterceptors = append(conf.RequestInterceptors, s.RequestInterceptor)
+}
+
+// Presign generates the presigned url of config, the url of config
+// should be absolute(with BaseURL) and the payload is unsigned
+func (s *SigV4Signer) Presign(config *Config, expires time.Duration) (string, error) {
+	method := config.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	u, err := url.Parse(config.GetURL())
+	if err != nil {
+		return "", err
+	}
+	t := s.now().UTC()
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.config.SessionToken != "" {
+		query.Set(headerAmzSecurityToken, s.config.SessionToken)
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		s.canonicalURI(u),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		SigV4UnsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(canonicalRequest, t))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
@@ -0,0 +1,435 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerLastEventID = "Last-Event-ID"
+
+	contentTypeEventStream = "text/event-stream"
+	// sseRetryInterval default reconnection interval of event stream
+	sseRetryInterval = 3 * time.Second
+	// sseMaxLineSize max size of a line in event stream
+	sseMaxLineSize = 1024 * 1024
+)
+
+var ErrSSEContentTypeInvalid = errors.New("content type of event stream is invalid")
+
+type (
+	// SSEEvent event of server-sent events
+	SSEEvent struct {
+		// ID the last event id
+		ID string
+		// Event the event type, default is message
+		Event string
+		// Data the data of event, the lines are joined with "\n"
+		Data string
+	}
+
+	// SSEConfig config of server-sent events
+	SSEConfig struct {
+		// Method the method of request, default is GET
+		Method string
+		// Headers the headers of request
+		Headers http.Header
+		// Params params for request route
+		Params map[string]string
+		// Query query for request
+		Query url.Values
+		// Body the body of request, it's transformed again for each connection
+		Body interface{}
+		// LastEventID the Last-Event-ID header of the first connection
+		LastEventID string
+		// RetryInterval the reconnection interval, default is 3s,
+		// it's replaced by the retry field of event stream
+		RetryInterval time.Duration
+		// MaxReconnects max count of consecutive reconnection failures,
+		// no limit if it's 0, and reconnection is disabled if it's lt 0
+		MaxReconnects int
+		// Timeout the timeout of connecting(until the response is received),
+		// the timeout of instance is used if it's 0
+		Timeout time.Duration
+		// BufferSize the buffer size of events channel
+		BufferSize int
+		// OnEvent the callback of event, the events are not sent to channel if it's set
+		OnEvent func(event *SSEEvent)
+	}
+
+	// SSEStream the stream of server-sent events
+	SSEStream struct {
+		mutex       sync.Mutex
+		events      chan *SSEEvent
+		done        chan struct{}
+		cancel      context.CancelFunc
+		closed      bool
+		err         error
+		lastEventID string
+		retry       time.Duration
+	}
+
+	// sseParser parses the event stream
+	sseParser struct {
+		scanner *bufio.Scanner
+		first   bool
+		// idBuffer the id of the event which is being parsed
+		idBuffer string
+		// lastEventID the id of the last dispatched event
+		lastEventID string
+		retry       time.Duration
+	}
+)
+
+// scanSSELines splits the event stream to lines, the line ends with CRLF, LF or CR
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	i := bytes.IndexAny(data, "\r\n")
+	if i == -1 {
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+	if data[i] == '\n' {
+		return i + 1, data[:i], nil
+	}
+	if i+1 < len(data) {
+		if data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	// 需要判断CR之后是否为LF
+	if !atEOF {
+		return 0, nil, nil
+	}
+	return i + 1, data[:i], nil
+}
+
+func newSSEParser(r io.Reader, lastEventID string) *sseParser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), sseMaxLineSize)
+	scanner.Split(scanSSELines)
+	return &sseParser{
+		scanner:     scanner,
+		first:       true,
+		idBuffer:    lastEventID,
+		lastEventID: lastEventID,
+	}
+}
+
+// next returns the next event of stream, the incomplete event is discarded at EOF,
+// and the last event id is updated only when the event is dispatched
+func (p *sseParser) next() (*SSEEvent, error) {
+	eventType := ""
+	data := new(strings.Builder)
+	hasData := false
+	for p.scanner.Scan() {
+		line := p.scanner.Text()
+		if p.first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			p.first = false
+		}
+		// 空行则分发事件
+		if line == "" {
+			p.lastEventID = p.idBuffer
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			return &SSEEvent{
+				ID:    p.lastEventID,
+				Event: eventType,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+			}, nil
+		}
+		// 注释
+		if line[0] == ':' {
+			continue
+		}
+		field, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimPrefix(value, " ")
+		}
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			hasData = true
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				p.idBuffer = value
+			}
+		case "retry":
+			ms, err := strconv.ParseUint(value, 10, 32)
+			if err == nil {
+				p.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	err := p.scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	return nil, err
+}
+
+// Events returns the channel of events, it's closed when the stream is done
+func (s *SSEStream) Events() <-chan *SSEEvent {
+	return s.events
+}
+
+// Done returns a channel which is closed when the stream is done
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error which stops the stream, it's nil if the stream
+// is closed by Close or the server responds 204
+func (s *SSEStream) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// LastEventID returns the last event id of stream
+func (s *SSEStream) LastEventID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastEventID
+}
+
+// Close closes the stream and waits for it's done
+func (s *SSEStream) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+	s.cancel()
+	<-s.done
+}
+
+func (s *SSEStream) setErr(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.err = err
+	}
+}
+
+// connectSSE connects to the event stream, it returns nil body if the response is 204
+func (ins *Instance) connectSSE(ctx context.Context, rawURL string, conf *SSEConfig, lastEventID string) (io.ReadCloser, error) {
+	headers := conf.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Set("Accept", contentTypeEventStream)
+	headers.Set("Cache-Control", "no-cache")
+	if lastEventID != "" {
+		headers.Set(headerLastEventID, lastEventID)
+	}
+	config := &Config{
+		URL:     rawURL,
+		Method:  conf.Method,
+		Headers: headers,
+		Params:  conf.Params,
+		Query:   conf.Query,
+		Body:    conf.Body,
+	}
+	resp, err := ins.requestStream(ctx, config, conf.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.Body
+	if resp.Status == http.StatusNoContent {
+		if body != nil {
+			_ = body.Close()
+		}
+		return nil, nil
+	}
+	if resp.Status != http.StatusOK || body == nil {
+		if body != nil {
+			_ = body.Close()
+		}
+		return nil, newHTTPError(config, resp)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers.Get(headerContentType))
+	if mediaType != contentTypeEventStream {
+		_ = body.Close()
+		return nil, ErrSSEContentTypeInvalid
+	}
+	return body, nil
+}
+
+// read reads the events of body until it's done, it returns true if any event is read
+func (s *SSEStream) read(ctx context.Context, body io.ReadCloser, conf *SSEConfig) (bool, error) {
+	done := make(chan struct{})
+	defer close(done)
+	// context取消时关闭body，避免阻塞在读取中
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = body.Close()
+		case <-done:
+		}
+	}()
+	defer body.Close()
+	parser := newSSEParser(body, s.LastEventID())
+	received := false
+	for {
+		event, err := parser.next()
+		s.mutex.Lock()
+		s.lastEventID = parser.lastEventID
+		if parser.retry != 0 {
+			s.retry = parser.retry
+		}
+		s.mutex.Unlock()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		if conf.OnEvent != nil {
+			conf.OnEvent(event)
+			continue
+		}
+		select {
+		case s.events <- event:
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+	}
+}
+
+// runSSE reads the events and reconnects until the stream is done
+func (ins *Instance) runSSE(ctx context.Context, s *SSEStream, rawURL string, conf *SSEConfig, body io.ReadCloser) {
+	defer close(s.done)
+	defer close(s.events)
+	defer s.cancel()
+	failures := 0
+	for {
+		received, err := s.read(ctx, body, conf)
+		if ctx.Err() != nil {
+			s.setErr(ctx.Err())
+			return
+		}
+		if received {
+			failures = 0
+		}
+		// 读取失败或服务端关闭连接时，等待后重连
+		for {
+			if conf.MaxReconnects < 0 || (conf.MaxReconnects > 0 && failures >= conf.MaxReconnects) {
+				// 服务端正常关闭则不设置出错
+				if err != io.EOF {
+					s.setErr(err)
+				}
+				return
+			}
+			failures++
+			s.mutex.Lock()
+			retry := s.retry
+			lastEventID := s.lastEventID
+			s.mutex.Unlock()
+			timer := time.NewTimer(retry)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				s.setErr(ctx.Err())
+				return
+			case <-timer.C:
+			}
+			body, err = ins.connectSSE(ctx, rawURL, conf, lastEventID)
+			if ctx.Err() != nil {
+				s.setErr(ctx.Err())
+				return
+			}
+			// 204则停止
+			if err == nil && body == nil {
+				return
+			}
+			if err == nil {
+				break
+			}
+			// 非网络出错(如响应状态码或类型不符)则不再重连
+			he := &HTTPError{}
+			if errors.As(err, &he) || errors.Is(err, ErrSSEContentTypeInvalid) {
+				s.setErr(err)
+				return
+			}
+		}
+	}
+}
+
+// SSE connects to the server-sent events of url, it returns error if the first
+// connection fails. The events are sent to the channel of stream or the OnEvent
+// callback, and it reconnects with Last-Event-ID header if the connection is lost.
+func (ins *Instance) SSE(ctx context.Context, rawURL string, configs ...*SSEConfig) (*SSEStream, error) {
+	conf := &SSEConfig{}
+	if len(configs) != 0 && configs[0] != nil {
+		c := *configs[0]
+		conf = &c
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	retry := conf.RetryInterval
+	if retry <= 0 {
+		retry = sseRetryInterval
+	}
+	body, err := ins.connectSSE(ctx, rawURL, conf, conf.LastEventID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &SSEStream{
+		events:      make(chan *SSEEvent, conf.BufferSize),
+		done:        make(chan struct{}),
+		cancel:      cancel,
+		lastEventID: conf.LastEventID,
+		retry:       retry,
+	}
+	// 首次请求返回204
+	if body == nil {
+		cancel()
+		close(s.events)
+		close(s.done)
+		return s, nil
+	}
+	go ins.runSSE(ctx, s, rawURL, conf, body)
+	return s, nil
+}
+
+// SSE connects to the server-sent events of url by default instance
+func SSE(ctx context.Context, rawURL string, configs ...*SSEConfig) (*SSEStream, error) {
+	return defaultIns.SSE(ctx, rawURL, configs...)
+}
@@ -0,0 +1,270 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEParser(t *testing.T) {
+	assert := assert.New(t)
+
+	stream := "\ufeff: comment\r\n" +
+		"event: update\r\n" +
+		"data: line1\r\n" +
+		"data:line2\r\n" +
+		"id: 1\r\n" +
+		"retry: 1500\r\n" +
+		"\r\n" +
+		// 仅CR结尾
+		"data: a\rdata\r\r" +
+		"id\n" +
+		"retry: abc\n" +
+		"data: b\n\n" +
+		// 无数据的事件不分发
+		"event: ping\n\n" +
+		"id: \x00\n" +
+		"data: c\n\n" +
+		// 未完成的事件丢弃，其id也不生效
+		"id: 2\n" +
+		"data: incomplete"
+	p := newSSEParser(strings.NewReader(stream), "0")
+	events := make([]*SSEEvent, 0)
+	for {
+		event, err := p.next()
+		if err != nil {
+			assert.Equal(io.EOF, err)
+			break
+		}
+		events = append(events, event)
+	}
+	assert.Equal([]*SSEEvent{
+		{
+			ID:    "1",
+			Event: "update",
+			Data:  "line1\nline2",
+		},
+		{
+			ID:    "1",
+			Event: "message",
+			Data:  "a\n",
+		},
+		{
+			ID:    "",
+			Event: "message",
+			Data:  "b",
+		},
+		{
+			ID:    "",
+			Event: "message",
+			Data:  "c",
+		},
+	}, events)
+	assert.Equal(1500*time.Millisecond, p.retry)
+	assert.Equal("", p.lastEventID)
+}
+
+func TestSSE(t *testing.T) {
+	assert := assert.New(t)
+
+	var connections int32
+	lastEventIDs := make([]string, 0)
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&connections, 1)
+		mutex.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get(headerLastEventID))
+		mutex.Unlock()
+		if r.Header.Get("X-Token") != "token" || r.Header.Get("Accept") != contentTypeEventStream {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if count == 3 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set(headerContentType, "text/event-stream; charset=utf-8")
+		flusher := w.(http.Flusher)
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		for i := 0; i < 2; i++ {
+			// 发送间隔大于实例的超时
+			time.Sleep(30 * time.Millisecond)
+			id := int(count-1)*2 + i + 1
+			_, _ = fmt.Fprintf(w, "retry: 10\nid: %d\ndata: %d\n\n", id, id)
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+		Timeout: 20 * time.Millisecond,
+		Headers: http.Header{
+			"X-Token": []string{"token"},
+		},
+	})
+	var intercepted int32
+	ins.AppendRequestInterceptor(func(config *Config) error {
+		atomic.AddInt32(&intercepted, 1)
+		return nil
+	})
+	stream, err := ins.SSE(context.Background(), "/events", &SSEConfig{
+		LastEventID: "0",
+	})
+	assert.Nil(err)
+	data := make([]string, 0)
+	for event := range stream.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Nil(stream.Err())
+	assert.Equal([]string{
+		"1",
+		"2",
+		"3",
+		"4",
+	}, data)
+	assert.Equal("4", stream.LastEventID())
+	assert.Equal([]string{
+		"0",
+		"2",
+		"4",
+	}, lastEventIDs)
+	assert.Equal(int32(3), intercepted)
+
+	// callback
+	atomic.StoreInt32(&connections, 0)
+	ids := make([]string, 0)
+	stream, err = ins.SSE(context.Background(), "/events", &SSEConfig{
+		MaxReconnects: -1,
+		OnEvent: func(event *SSEEvent) {
+			ids = append(ids, event.ID)
+		},
+	})
+	assert.Nil(err)
+	<-stream.Done()
+	assert.Nil(stream.Err())
+	assert.Equal([]string{
+		"1",
+		"2",
+	}, ids)
+
+	// 认证失败
+	_, err = NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	}).SSE(context.Background(), "/events")
+	he := &HTTPError{}
+	assert.True(errors.As(err, &he))
+	assert.Equal(http.StatusUnauthorized, he.Status)
+}
+
+func TestSSEReconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	var connections int32
+	lastEventIDs := make([]string, 0)
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&connections, 1)
+		mutex.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get(headerLastEventID))
+		mutex.Unlock()
+		switch count {
+		case 1:
+			w.Header().Set(headerContentType, contentTypeEventStream)
+			// 第二个事件未完成时断开连接
+			_, _ = w.Write([]byte("retry: 10\nid: 1\ndata: 1\n\nid: 2\ndata: 2"))
+		case 2:
+			w.Header().Set(headerContentType, contentTypeEventStream)
+			_, _ = w.Write([]byte("id: 2\ndata: 2\n\n"))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	stream, err := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	}).SSE(context.Background(), "/events")
+	assert.Nil(err)
+	data := make([]string, 0)
+	for event := range stream.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Nil(stream.Err())
+	assert.Equal([]string{
+		"1",
+		"2",
+	}, data)
+	// 重连时的Last-Event-ID为最后分发的事件
+	assert.Equal([]string{
+		"",
+		"1",
+		"2",
+	}, lastEventIDs)
+}
+
+func TestSSEClose(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+		w.Header().Set(headerContentType, contentTypeEventStream)
+		_, _ = w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+	})
+	_, err := ins.SSE(context.Background(), "/json")
+	assert.Equal(ErrSSEContentTypeInvalid, err)
+
+	stream, err := ins.SSE(context.Background(), "/events")
+	assert.Nil(err)
+	event := <-stream.Events()
+	assert.Equal("hello", event.Data)
+	stream.Close()
+	_, ok := <-stream.Events()
+	assert.False(ok)
+	assert.Nil(stream.Err())
+	assert.Equal(uint32(0), ins.GetConcurrency())
+
+	// context取消
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = ins.SSE(ctx, "/events")
+	assert.Nil(err)
+	<-stream.Events()
+	cancel()
+	<-stream.Done()
+	assert.Equal(context.Canceled, stream.Err())
+}
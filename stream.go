@@ -0,0 +1,175 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamDecoders decoders of content encoding for stream response
+var streamDecoders = map[string]newReader{
+	gzipEncoding: newGzipReader,
+	brEncoding:   newBrReader,
+}
+
+// streamBody body of stream response,
+// the close hooks are called once when it's closed
+type streamBody struct {
+	mutex  sync.Mutex
+	reader io.Reader
+	raw    io.ReadCloser
+	// 是否已使用解码的reader
+	decoded bool
+	size    int
+	err     error
+	closed  bool
+	hooks   []func(err error)
+}
+
+// newStreamBody creates a stream body,
+// the body is decoded if the content encoding is supported
+func newStreamBody(raw io.ReadCloser, headers http.Header) (*streamBody, error) {
+	sb := &streamBody{
+		reader: raw,
+		raw:    raw,
+	}
+	if headers == nil {
+		return sb, nil
+	}
+	fn, ok := streamDecoders[strings.ToLower(headers.Get(headerContentEncoding))]
+	if !ok {
+		return sb, nil
+	}
+	r, err := fn(raw)
+	if err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+	sb.reader = r
+	sb.decoded = true
+	// 解压后删除encoding以及content-length
+	headers.Del(headerContentEncoding)
+	headers.Del(headerContentLength)
+	return sb, nil
+}
+
+func (sb *streamBody) Read(p []byte) (int, error) {
+	n, err := sb.reader.Read(p)
+	sb.mutex.Lock()
+	sb.size += n
+	if err != nil && err != io.EOF && sb.err == nil {
+		sb.err = err
+	}
+	sb.mutex.Unlock()
+	return n, err
+}
+
+// Close closes the body and calls the close hooks
+func (sb *streamBody) Close() error {
+	sb.mutex.Lock()
+	if sb.closed {
+		sb.mutex.Unlock()
+		return nil
+	}
+	sb.closed = true
+	hooks := sb.hooks
+	sb.hooks = nil
+	readErr := sb.err
+	sb.mutex.Unlock()
+
+	if closer, ok := sb.reader.(io.Closer); ok && sb.decoded {
+		_ = closer.Close()
+	}
+	err := sb.raw.Close()
+	for _, fn := range hooks {
+		fn(readErr)
+	}
+	return err
+}
+
+// onClose adds hook which is called when the body is closed,
+// it's called immediately if the body has been closed
+func (sb *streamBody) onClose(fn func(err error)) {
+	sb.mutex.Lock()
+	if !sb.closed {
+		sb.hooks = append(sb.hooks, fn)
+		sb.mutex.Unlock()
+		return
+	}
+	readErr := sb.err
+	sb.mutex.Unlock()
+	fn(readErr)
+}
+
+// bytesRead returns the size of data which has been read
+func (sb *streamBody) bytesRead() int {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.size
+}
+
+// getStreamBody returns the stream body of response
+func getStreamBody(resp *Response) *streamBody {
+	if resp == nil {
+		return nil
+	}
+	sb, _ := resp.Body.(*streamBody)
+	return sb
+}
+
+// requestStream does the stream request, the timeout(the timeout of instance if it's 0)
+// is only for receiving the response, not including reading the body.
+// The context of request is cancelled when the body is closed.
+func (ins *Instance) requestStream(ctx context.Context, config *Config, timeout time.Duration) (*Response, error) {
+	if timeout == 0 {
+		timeout = ins.Config.Timeout
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	config.Stream = true
+	config.noTimeout = true
+	ctx, cancel := context.WithCancel(ctx)
+	config.Context = ctx
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, cancel)
+	}
+	resp, err := ins.Request(config)
+	// 定时器已触发则为超时
+	if timer != nil && !timer.Stop() && ctx.Err() != nil {
+		err = context.DeadlineExceeded
+	}
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		cancel()
+		return nil, err
+	}
+	if sb := getStreamBody(resp); sb != nil {
+		sb.onClose(func(_ error) {
+			cancel()
+		})
+	} else {
+		cancel()
+	}
+	return resp, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamBody(t *testing.T) {
+	assert := assert.New(t)
+
+	sb, err := newStreamBody(io.NopCloser(strings.NewReader("abc")), nil)
+	assert.Nil(err)
+	closedCount := 0
+	sb.onClose(func(err error) {
+		assert.Nil(err)
+		closedCount++
+	})
+	buf, err := io.ReadAll(sb)
+	assert.Nil(err)
+	assert.Equal("abc", string(buf))
+	assert.Equal(3, sb.bytesRead())
+	assert.Nil(sb.Close())
+	assert.Nil(sb.Close())
+	assert.Equal(1, closedCount)
+	// 已关闭则直接调用
+	sb.onClose(func(err error) {
+		closedCount++
+	})
+	assert.Equal(2, closedCount)
+
+	headers := make(http.Header)
+	headers.Set(headerContentEncoding, "gzip")
+	_, err = newStreamBody(io.NopCloser(strings.NewReader("abc")), headers)
+	assert.NotNil(err)
+}
+
+func TestStreamRequest(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentEncoding, "gzip")
+		gw := gzip.NewWriter(w)
+		for i := 0; i < 3; i++ {
+			_, _ = gw.Write([]byte("data\n"))
+			_ = gw.Flush()
+			w.(http.Flusher).Flush()
+		}
+		_ = gw.Close()
+	}))
+	defer server.Close()
+
+	done := false
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: server.URL,
+		Timeout: time.Second,
+		Client: &http.Client{
+			Transport: &http.Transport{},
+		},
+		OnDone: func(config *Config, resp *Response, err error) {
+			done = true
+		},
+	})
+	resp, err := ins.Request(&Config{
+		URL:    "/",
+		Stream: true,
+	})
+	assert.Nil(err)
+	assert.Nil(resp.Data)
+	assert.Empty(resp.Headers.Get(headerContentEncoding))
+	// 未关闭前不释放
+	assert.False(done)
+	assert.Equal(uint32(1), ins.GetConcurrency())
+
+	buf, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Equal("data\ndata\ndata\n", string(buf))
+	assert.Nil(resp.Body.Close())
+	assert.True(done)
+	assert.Equal(uint32(0), ins.GetConcurrency())
+	assert.Equal(15, GetStats(resp.Config, nil).Size)
+}
+
+func TestStreamInterceptorError(t *testing.T) {
+	assert := assert.New(t)
+	customErr := errors.New("custom error")
+	closed := false
+	ins := NewInstance(&InstanceConfig{
+		Adapter: func(config *Config) (*Response, error) {
+			return &Response{
+				Status: 200,
+				Body: &mockReadCloser{
+					Reader: strings.NewReader("abc"),
+					onClose: func() {
+						closed = true
+					},
+				},
+			}, nil
+		},
+		ResponseInterceptors: []ResponseInterceptor{
+			func(resp *Response) error {
+				return customErr
+			},
+		},
+	})
+	_, err := ins.Request(&Config{
+		URL:    "/",
+		Stream: true,
+	})
+	assert.Equal(customErr, err)
+	assert.True(closed)
+	assert.Equal(uint32(0), ins.GetConcurrency())
+}
+
+type mockReadCloser struct {
+	io.Reader
+	onClose func()
+}
+
+func (m *mockReadCloser) Close() error {
+	m.onClose()
+	return nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerTraceparent = "Traceparent"
+	headerTracestate  = "Tracestate"
+
+	// TraceFlagSampled the sampled flag of trace context
+	TraceFlagSampled byte = 0x01
+
+	// tracingSpan the key of span in config
+	tracingSpan = "_tracingSpan"
+)
+
+var ErrInvalidTraceparent = errors.New("traceparent is invalid")
+
+type traceContextKey struct{}
+
+type (
+	// TraceContext w3c trace context
+	TraceContext struct {
+		// TraceID the id of trace
+		TraceID [16]byte
+		// SpanID the id of parent span
+		SpanID [8]byte
+		// Flags the trace flags
+		Flags byte
+		// State the vendor-specific trace state
+		State string
+	}
+
+	// Span client span of request
+	Span struct {
+		TraceID       string                   `json:"traceId"`
+		SpanID        string                   `json:"spanId"`
+		ParentSpanID  string                   `json:"parentSpanId,omitempty"`
+		Name          string                   `json:"name"`
+		Method        string                   `json:"method"`
+		Route         string                   `json:"route,omitempty"`
+		URL           string                   `json:"url"`
+		Status        int                      `json:"status"`
+		ErrorCategory string                   `json:"errorCategory,omitempty"`
+		Error         string                   `json:"error,omitempty"`
+		Attempts      int                      `json:"attempts,omitempty"`
+		Start         time.Time                `json:"start"`
+		End           time.Time                `json:"end"`
+		Phases        map[string]time.Duration `json:"phases,omitempty"`
+	}
+
+	// SpanExporter exports the finished span
+	SpanExporter interface {
+		Export(span *Span) error
+	}
+
+	// Tracer propagates the trace context of request and exports client spans
+	Tracer struct {
+		exporter SpanExporter
+	}
+
+	// JSONLinesExporter exports span as one json per line
+	JSONLinesExporter struct {
+		mutex sync.Mutex
+		w     io.Writer
+	}
+)
+
+func isZeroBytes(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerHex(value string) bool {
+	for _, c := range value {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceparent parses the traceparent header
+func ParseTraceparent(value string) (TraceContext, error) {
+	tc := TraceContext{}
+	value = strings.TrimSpace(value)
+	// version-traceid-parentid-flags
+	if len(value) < 55 || !isLowerHex(value[:2]) || value[:2] == "ff" {
+		return tc, ErrInvalidTraceparent
+	}
+	// 00版本长度固定，未来版本可在后面追加字段
+	if (value[:2] == "00" && len(value) != 55) || (len(value) > 55 && value[55] != '-') {
+		return tc, ErrInvalidTraceparent
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return tc, ErrInvalidTraceparent
+	}
+	traceID := value[3:35]
+	spanID := value[36:52]
+	flags := value[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return tc, ErrInvalidTraceparent
+	}
+	_, _ = hex.Decode(tc.TraceID[:], []byte(traceID))
+	_, _ = hex.Decode(tc.SpanID[:], []byte(spanID))
+	buf, _ := hex.DecodeString(flags)
+	tc.Flags = buf[0]
+	if !tc.IsValid() {
+		return tc, ErrInvalidTraceparent
+	}
+	return tc, nil
+}
+
+// TraceContextFromHeader gets trace context from the traceparent and tracestate header
+func TraceContextFromHeader(header http.Header) (TraceContext, bool) {
+	tc, err := ParseTraceparent(header.Get(headerTraceparent))
+	if err != nil {
+		return tc, false
+	}
+	tc.State = strings.Join(header.Values(headerTracestate), ",")
+	return tc, true
+}
+
+// ContextWithTrace returns a copy of context with trace context
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceFromContext gets trace context from context
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	if !ok || !tc.IsValid() {
+		return TraceContext{}, false
+	}
+	return tc, true
+}
+
+// IsValid returns true if both trace id and span id are not zero
+func (tc TraceContext) IsValid() bool {
+	return !isZeroBytes(tc.TraceID[:]) && !isZeroBytes(tc.SpanID[:])
+}
+
+// Sampled returns true if the sampled flag is set
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&TraceFlagSampled != 0
+}
+
+// Traceparent returns the value of traceparent header
+func (tc TraceContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(tc.TraceID[:]) + "-" + hex.EncodeToString(tc.SpanID[:]) + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// newTraceID generates random id which is not zero
+func newTraceID(data []byte) {
+	for {
+		_, _ = rand.Read(data)
+		if !isZeroBytes(data) {
+			return
+		}
+	}
+}
+
+// NewTracer creates a tracer, the spans are not exported if exporter is nil
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// Register adds the listeners of tracer to instance
+func (t *Tracer) Register(ins *Instance) {
+	ins.Config.AddBeforeNewRequestListener(t.OnBeforeNewRequest)
+	ins.Config.AddDoneListener(t.OnDone)
+}
+
+// OnBeforeNewRequest starts the span of request, the trace context is read
+// from Config.Context or created if not exists, and injects the
+// traceparent and tracestate headers
+func (t *Tracer) OnBeforeNewRequest(config *Config) error {
+	parent, ok := TraceFromContext(config.Context)
+	tc := TraceContext{
+		Flags: TraceFlagSampled,
+	}
+	span := &Span{
+		Start: time.Now(),
+	}
+	if ok {
+		tc.TraceID = parent.TraceID
+		tc.Flags = parent.Flags
+		tc.State = parent.State
+		span.ParentSpanID = hex.EncodeToString(parent.SpanID[:])
+	} else {
+		newTraceID(tc.TraceID[:])
+	}
+	newTraceID(tc.SpanID[:])
+	span.TraceID = hex.EncodeToString(tc.TraceID[:])
+	span.SpanID = hex.EncodeToString(tc.SpanID[:])
+
+	if config.Headers == nil {
+		config.Headers = make(http.Header)
+	}
+	config.Headers.Set(headerTraceparent, tc.Traceparent())
+	if tc.State != "" {
+		config.Headers.Set(headerTracestate, tc.State)
+	}
+	// 未采样的不导出span
+	if tc.Sampled() {
+		config.Set(tracingSpan, span)
+	}
+	return nil
+}
+
+// OnDone ends the span of request and exports it
+func (t *Tracer) OnDone(config *Config, resp *Response, err error) {
+	span, ok := config.Get(tracingSpan).(*Span)
+	if !ok || t.exporter == nil {
+		return
+	}
+	span.End = time.Now()
+	span.Method = config.Method
+	span.Route = config.Route
+	span.URL = config.GetURL()
+	span.Name = span.Method + " " + config.getRoute()
+	span.Attempts = config.Attempts
+	span.Status = -1
+	if resp != nil {
+		span.Status = resp.Status
+	}
+	if err != nil {
+		span.Error = err.Error()
+		span.ErrorCategory = GetInternalErrorCategory(err)
+		if span.ErrorCategory == "" {
+			span.ErrorCategory = ErrCategoryOther
+		}
+	}
+	if ht := config.HTTPTrace; ht != nil {
+		timelineStats := ht.Stats()
+		span.Phases = map[string]time.Duration{
+			"dns":              timelineStats.DNSLookup,
+			"tcp":              timelineStats.TCPConnection,
+			"tls":              timelineStats.TLSHandshake,
+			"requestSend":      timelineStats.RequestSend,
+			"serverProcessing": timelineStats.ServerProcessing,
+			"contentTransfer":  timelineStats.ContentTransfer,
+		}
+	}
+	_ = t.exporter.Export(span)
+}
+
+// NewJSONLinesExporter creates an exporter which writes span as json lines
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{
+		w: w,
+	}
+}
+
+// Export writes the span as one json line
+func (e *JSONLinesExporter) Export(span *Span) error {
+	buf, err := jsonMarshal(span)
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	_, err = e.w.Write(append(buf, '\n'))
+	return err
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	assert := assert.New(t)
+
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tc, err := ParseTraceparent(value)
+	assert.Nil(err)
+	assert.True(tc.Sampled())
+	assert.Equal(value, tc.Traceparent())
+
+	tc, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-abc")
+	assert.Nil(err)
+	assert.False(tc.Sampled())
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-abc",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(value)
+		assert.Equal(ErrInvalidTraceparent, err, value)
+	}
+
+	header := http.Header{}
+	header.Set(headerTraceparent, value)
+	header.Add(headerTracestate, "a=1")
+	header.Add(headerTracestate, "b=2")
+	tc, ok := TraceContextFromHeader(header)
+	assert.True(ok)
+	assert.Equal("a=1,b=2", tc.State)
+
+	ctx := ContextWithTrace(context.Background(), tc)
+	result, ok := TraceFromContext(ctx)
+	assert.True(ok)
+	assert.Equal(tc, result)
+	_, ok = TraceFromContext(context.Background())
+	assert.False(ok)
+}
+
+func TestTracer(t *testing.T) {
+	assert := assert.New(t)
+
+	var headers []http.Header
+	ins := NewInstance(&InstanceConfig{
+		BaseURL: "https://aslant.site",
+		Adapter: func(config *Config) (*Response, error) {
+			headers = append(headers, config.Request.Header.Clone())
+			if config.Route == "/error" {
+				return nil, errors.New("custom error")
+			}
+			return &Response{
+				Status: 200,
+			}, nil
+		},
+	})
+	buf := &bytes.Buffer{}
+	NewTracer(NewJSONLinesExporter(buf)).Register(ins)
+
+	// 新建trace
+	_, err := ins.Get("/users/me")
+	assert.Nil(err)
+	tc, err := ParseTraceparent(headers[0].Get(headerTraceparent))
+	assert.Nil(err)
+	assert.True(tc.Sampled())
+
+	// 从context中读取trace
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.State = "a=1"
+	_, err = ins.GetX(ContextWithTrace(context.Background(), parent), "/error")
+	assert.NotNil(err)
+	tc, err = ParseTraceparent(headers[1].Get(headerTraceparent))
+	assert.Nil(err)
+	assert.Equal(parent.TraceID, tc.TraceID)
+	assert.NotEqual(parent.SpanID, tc.SpanID)
+	assert.Equal("a=1", headers[1].Get(headerTracestate))
+
+	// 未采样的请求传递trace但不导出span
+	parent.Flags = 0
+	_, err = ins.GetX(ContextWithTrace(context.Background(), parent), "/users/me")
+	assert.Nil(err)
+	assert.True(strings.HasSuffix(headers[2].Get(headerTraceparent), "-00"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(2, len(lines))
+	span := Span{}
+	assert.Nil(json.Unmarshal([]byte(lines[0]), &span))
+	assert.Equal("GET /users/me", span.Name)
+	assert.Equal(200, span.Status)
+	assert.Empty(span.ParentSpanID)
+	assert.False(span.End.Before(span.Start))
+
+	span = Span{}
+	assert.Nil(json.Unmarshal([]byte(lines[1]), &span))
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+	assert.Equal("00f067aa0ba902b7", span.ParentSpanID)
+	assert.Equal(-1, span.Status)
+	assert.Equal(ErrCategoryOther, span.ErrorCategory)
+	assert.Equal("custom error", span.Error)
+}
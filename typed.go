@@ -0,0 +1,228 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type (
+	// ResponseError error of non-2xx response with the typed payload,
+	// it's based on *HTTPError(status, headers, body and url of request)
+	ResponseError[E any] struct {
+		*HTTPError
+		// Payload the payload decoded from response data
+		Payload E
+		// DecodeErr the error of decoding payload(e.g. html page of 502),
+		// the payload is zero value if it's not nil
+		DecodeErr error
+		// Response the http response
+		Response *Response
+	}
+
+	// Typed typed request helpers of instance,
+	// the data of 2xx response is decoded to T, and the data of
+	// non-2xx response is decoded to E and returned as *ResponseError[E]
+	Typed[T any, E any] struct {
+		ins         *Instance
+		decodeError bool
+	}
+
+	typedDecoder[T any, E any] struct {
+		result      T
+		decodeError bool
+	}
+)
+
+func (e *ResponseError[E]) Error() string {
+	return fmt.Sprintf("request fail, status: %d", e.Status)
+}
+
+// Unwrap returns the http error
+func (e *ResponseError[E]) Unwrap() error {
+	return e.HTTPError
+}
+
+func isSuccessStatus(status int) bool {
+	return status >= 200 && status < 300
+}
+
+func (d *typedDecoder[T, E]) decode(resp *Response, err error) error {
+	if d.decodeError && !isSuccessStatus(resp.Status) {
+		// 已校验状态码(ValidateStatus)则基于其http error
+		var he *HTTPError
+		if !errors.As(err, &he) {
+			config := resp.Config
+			if config == nil {
+				config = &Config{}
+			}
+			he = newHTTPError(config, resp)
+		}
+		re := &ResponseError[E]{
+			HTTPError: he,
+			Response:  resp,
+		}
+		// 解析失败时依然返回response error，避免丢失状态码
+		if len(resp.Data) != 0 {
+			re.DecodeErr = jsonUnmarshal(resp.Data, &re.Payload)
+		}
+		return re
+	}
+	if err != nil {
+		return err
+	}
+	// 无响应数据(如HEAD请求)则返回零值
+	if len(resp.Data) == 0 {
+		return nil
+	}
+	return resp.JSON(&d.result)
+}
+
+// NewTyped creates typed request helpers of instance
+func NewTyped[T any, E any](ins *Instance) *Typed[T, E] {
+	return &Typed[T, E]{
+		ins:         ins,
+		decodeError: true,
+	}
+}
+
+func newTypedConfig(ctx context.Context, method, url string, data interface{}, query []url.Values) *Config {
+	config := &Config{
+		Context: ctx,
+		URL:     url,
+		Method:  method,
+		Body:    data,
+	}
+	if len(query) != 0 {
+		config.Query = query[0]
+	}
+	return config
+}
+
+// Request http request and decode response to T
+func (t *Typed[T, E]) Request(config *Config) (T, *Response, error) {
+	decoder := &typedDecoder[T, E]{
+		decodeError: t.decodeError,
+	}
+	resp, err := t.ins.doRequest(config, decoder)
+	return decoder.result, resp, err
+}
+
+// Get http get request and decode response to T
+func (t *Typed[T, E]) Get(ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodGet, url, nil, query))
+}
+
+// Delete http delete request and decode response to T
+func (t *Typed[T, E]) Delete(ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodDelete, url, nil, query))
+}
+
+// Head http head request, the result is zero value if response data is empty
+func (t *Typed[T, E]) Head(ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodHead, url, nil, query))
+}
+
+// Options http options request and decode response to T
+func (t *Typed[T, E]) Options(ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodOptions, url, nil, query))
+}
+
+// Post http post request and decode response to T
+func (t *Typed[T, E]) Post(ctx context.Context, url string, data interface{}, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodPost, url, data, query))
+}
+
+// Put http put request and decode response to T
+func (t *Typed[T, E]) Put(ctx context.Context, url string, data interface{}, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodPut, url, data, query))
+}
+
+// Patch http patch request and decode response to T
+func (t *Typed[T, E]) Patch(ctx context.Context, url string, data interface{}, query ...url.Values) (T, *Response, error) {
+	return t.Request(newTypedConfig(ctx, http.MethodPatch, url, data, query))
+}
+
+// Upload uploads file and decode response to T
+func (t *Typed[T, E]) Upload(ctx context.Context, url string, file MultipartBody, query ...url.Values) (T, *Response, error) {
+	var result T
+	data, err := multipartBodyData(file)
+	if err != nil {
+		return result, nil, err
+	}
+	config := newTypedConfig(ctx, http.MethodPost, url, data, query)
+	config.Headers = http.Header{
+		headerContentType: {
+			file.FormDataContentType(),
+		},
+	}
+	return t.Request(config)
+}
+
+// newTypedAs creates typed request helpers which don't decode error payload
+func newTypedAs[T any](ins *Instance) *Typed[T, struct{}] {
+	return &Typed[T, struct{}]{
+		ins: ins,
+	}
+}
+
+// RequestAs http request and decode response to T
+func RequestAs[T any](ins *Instance, config *Config) (T, *Response, error) {
+	return newTypedAs[T](ins).Request(config)
+}
+
+// GetAs http get request and decode response to T
+func GetAs[T any](ins *Instance, ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Get(ctx, url, query...)
+}
+
+// DeleteAs http delete request and decode response to T
+func DeleteAs[T any](ins *Instance, ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Delete(ctx, url, query...)
+}
+
+// HeadAs http head request, the result is zero value if response data is empty
+func HeadAs[T any](ins *Instance, ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Head(ctx, url, query...)
+}
+
+// OptionsAs http options request and decode response to T
+func OptionsAs[T any](ins *Instance, ctx context.Context, url string, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Options(ctx, url, query...)
+}
+
+// PostAs http post request and decode response to T
+func PostAs[T any](ins *Instance, ctx context.Context, url string, data interface{}, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Post(ctx, url, data, query...)
+}
+
+// PutAs http put request and decode response to T
+func PutAs[T any](ins *Instance, ctx context.Context, url string, data interface{}, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Put(ctx, url, data, query...)
+}
+
+// PatchAs http patch request and decode response to T
+func PatchAs[T any](ins *Instance, ctx context.Context, url string, data interface{}, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Patch(ctx, url, data, query...)
+}
+
+// UploadAs uploads file and decode response to T
+func UploadAs[T any](ins *Instance, ctx context.Context, url string, file MultipartBody, query ...url.Values) (T, *Response, error) {
+	return newTypedAs[T](ins).Upload(ctx, url, file, query...)
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	type errorPayload struct {
+		Message string `json:"message"`
+	}
+	var onErrorErr error
+	ins := NewInstance(&InstanceConfig{
+		OnError: func(err error, config *Config) error {
+			onErrorErr = err
+			return nil
+		},
+		Adapter: func(config *Config) (*Response, error) {
+			switch config.Route {
+			case "/error":
+				return &Response{
+					Status: 400,
+					Data:   []byte(`{"message":"invalid"}`),
+				}, nil
+			case "/bad-gateway":
+				return &Response{
+					Status: 502,
+					Data:   []byte("<html>bad gateway</html>"),
+				}, nil
+			case "/head":
+				return &Response{
+					Status: 200,
+				}, nil
+			}
+			data := []byte(`{"name":"tree"}`)
+			if config.Request.Body != nil {
+				data, _ = io.ReadAll(config.Request.Body)
+			}
+			return &Response{
+				Status: 200,
+				Data:   data,
+			}, nil
+		},
+	})
+	ctx := context.Background()
+	expected := user{
+		Name: "tree",
+	}
+
+	fns := []func() (user, *Response, error){
+		func() (user, *Response, error) {
+			return RequestAs[user](ins, &Config{
+				URL: "/",
+			})
+		},
+		func() (user, *Response, error) {
+			return GetAs[user](ins, ctx, "/")
+		},
+		func() (user, *Response, error) {
+			return DeleteAs[user](ins, ctx, "/")
+		},
+		func() (user, *Response, error) {
+			return OptionsAs[user](ins, ctx, "/")
+		},
+		func() (user, *Response, error) {
+			return PostAs[user](ins, ctx, "/", expected)
+		},
+		func() (user, *Response, error) {
+			return PutAs[user](ins, ctx, "/", expected)
+		},
+		func() (user, *Response, error) {
+			return PatchAs[user](ins, ctx, "/", expected)
+		},
+	}
+	for _, fn := range fns {
+		result, resp, err := fn()
+		assert.Nil(err)
+		assert.Equal(200, resp.Status)
+		assert.Equal(expected, result)
+	}
+
+	result, _, err := HeadAs[user](ins, ctx, "/head")
+	assert.Nil(err)
+	assert.Equal(user{}, result)
+
+	ms := NewMultipartStream()
+	ms.AddFields(map[string]string{
+		"name": "tree",
+	})
+	_, resp, err := UploadAs[map[string]interface{}](ins, ctx, "/upload", ms)
+	// 上传的数据非json
+	assert.NotNil(err)
+	assert.Equal(200, resp.Status)
+
+	// 未指定出错类型，不处理非2xx的响应
+	_, resp, err = GetAs[user](ins, ctx, "/error")
+	assert.Nil(err)
+	assert.Equal(400, resp.Status)
+
+	typed := NewTyped[user, errorPayload](ins)
+	result, _, err = typed.Get(ctx, "/")
+	assert.Nil(err)
+	assert.Equal(expected, result)
+
+	_, _, err = typed.Get(ctx, "/error")
+	re := &ResponseError[errorPayload]{}
+	assert.True(errors.As(err, &re))
+	assert.Equal(400, re.Status)
+	assert.Equal("invalid", re.Payload.Message)
+	assert.Equal("request fail, status: 400", err.Error())
+	assert.Nil(re.DecodeErr)
+	// 基于http error
+	he := &HTTPError{}
+	assert.True(errors.As(err, &he))
+	assert.Equal(400, he.Status)
+	assert.Equal(`{"message":"invalid"}`, string(he.Body))
+	// error listener可获取response error
+	assert.Equal(err, onErrorErr)
+
+	// 非json的响应数据依然返回response error
+	_, resp, err = typed.Get(ctx, "/bad-gateway")
+	assert.True(errors.As(err, &re))
+	assert.Equal(502, re.Status)
+	assert.Equal(resp, re.Response)
+	assert.NotNil(re.DecodeErr)
+	assert.Equal(errorPayload{}, re.Payload)
+	assert.Equal("/bad-gateway", re.Route)
+}
+
+func TestTypedValidateStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	type errorPayload struct {
+		Message string `json:"message"`
+	}
+	var onErrorErrs []error
+	ins := NewInstance(&InstanceConfig{
+		ValidateStatus: DefaultValidateStatus,
+		OnError: func(err error, config *Config) error {
+			onErrorErrs = append(onErrorErrs, err)
+			return nil
+		},
+		Adapter: func(config *Config) (*Response, error) {
+			return &Response{
+				Status: 400,
+				Data:   []byte(`{"message":"invalid"}`),
+			}, nil
+		},
+	})
+	ctx := context.Background()
+
+	// 实例校验了状态码，依然返回response error
+	_, resp, err := NewTyped[map[string]string, errorPayload](ins).Get(ctx, "/error")
+	re := &ResponseError[errorPayload]{}
+	assert.True(errors.As(err, &re))
+	assert.Equal(400, re.Status)
+	assert.Equal("invalid", re.Payload.Message)
+	assert.Equal(resp, re.Response)
+	assert.Equal("/error", re.Route)
+	// error listener仅触发一次
+	assert.Equal([]error{err}, onErrorErrs)
+
+	// 未指定出错类型则返回http error
+	onErrorErrs = nil
+	_, _, err = GetAs[map[string]string](ins, ctx, "/error")
+	he := &HTTPError{}
+	assert.True(errors.As(err, &he))
+	assert.False(errors.As(err, &re))
+	assert.Equal(400, he.Status)
+	assert.Equal([]error{err}, onErrorErrs)
+}
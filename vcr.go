@@ -0,0 +1,326 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// VCRModeRecordMissing replays the recorded interaction, and records it if not found
+	VCRModeRecordMissing VCRMode = iota
+	// VCRModeRecord always sends the request and records the interaction,
+	// the cassette is overwritten
+	VCRModeRecord
+	// VCRModeReplay only replays the recorded interactions
+	VCRModeReplay
+)
+
+// vcrRedacted the value of redacted header
+const vcrRedacted = "[REDACTED]"
+
+var ErrVCRInteractionNotFound = errors.New("vcr interaction is not found")
+
+type (
+	// VCRMode mode of vcr
+	VCRMode int
+
+	// VCRRequest recorded request
+	VCRRequest struct {
+		Method  string      `json:"method"`
+		URL     string      `json:"url"`
+		Headers http.Header `json:"headers,omitempty"`
+		Body    []byte      `json:"body,omitempty"`
+	}
+	// VCRResponse recorded response
+	VCRResponse struct {
+		Status  int         `json:"status"`
+		Headers http.Header `json:"headers,omitempty"`
+		Data    []byte      `json:"data,omitempty"`
+	}
+	// VCRInteraction recorded request and response pair
+	VCRInteraction struct {
+		Request  *VCRRequest  `json:"request"`
+		Response *VCRResponse `json:"response"`
+	}
+	// VCRMatcher returns true if the request matches the recorded request
+	VCRMatcher func(req, recorded *VCRRequest) bool
+
+	// VCRConfig config of vcr
+	VCRConfig struct {
+		// Path the path of cassette file
+		Path string
+		// Mode the mode of vcr, default is record missing
+		Mode VCRMode
+		// Matchers matchers of request, default is matching method and url
+		Matchers []VCRMatcher
+		// RedactHeaders request and response headers whose values are
+		// redacted before being recorded
+		RedactHeaders []string
+		// Adapter the adapter which sends the request, default is http adapter
+		Adapter Adapter
+	}
+
+	// VCR records the request and response pairs to cassette file and replays them
+	VCR struct {
+		mutex        sync.Mutex
+		config       VCRConfig
+		interactions []*VCRInteraction
+		// used marks the replayed interactions
+		used map[*VCRInteraction]bool
+	}
+
+	vcrCassette struct {
+		Interactions []*VCRInteraction `json:"interactions"`
+	}
+)
+
+// VCRMatchMethod matches the method of request
+func VCRMatchMethod(req, recorded *VCRRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// VCRMatchURL matches the url of request
+func VCRMatchURL(req, recorded *VCRRequest) bool {
+	return req.URL == recorded.URL
+}
+
+// VCRMatchBody matches the body of request
+func VCRMatchBody(req, recorded *VCRRequest) bool {
+	return bytes.Equal(req.Body, recorded.Body)
+}
+
+// VCRMatchHeaders creates a matcher which matches the headers of request
+func VCRMatchHeaders(keys ...string) VCRMatcher {
+	return func(req, recorded *VCRRequest) bool {
+		for _, key := range keys {
+			if req.Headers.Get(key) != recorded.Headers.Get(key) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NewVCR creates a vcr, the cassette is loaded if the mode is not record
+func NewVCR(config VCRConfig) (*VCR, error) {
+	if len(config.Matchers) == 0 {
+		config.Matchers = []VCRMatcher{
+			VCRMatchMethod,
+			VCRMatchURL,
+		}
+	}
+	if config.Adapter == nil {
+		config.Adapter = defaultAdapter
+	}
+	v := &VCR{
+		config: config,
+		used:   make(map[*VCRInteraction]bool),
+	}
+	if config.Mode == VCRModeRecord {
+		return v, nil
+	}
+	buf, err := os.ReadFile(config.Path)
+	if err != nil {
+		// 未录制时允许cassette不存在
+		if os.IsNotExist(err) && config.Mode == VCRModeRecordMissing {
+			return v, nil
+		}
+		return nil, err
+	}
+	cassette := vcrCassette{}
+	err = json.Unmarshal(buf, &cassette)
+	if err != nil {
+		return nil, err
+	}
+	v.interactions = cassette.Interactions
+	return v, nil
+}
+
+// Interactions returns the recorded interactions
+func (v *VCR) Interactions() []*VCRInteraction {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	result := make([]*VCRInteraction, len(v.interactions))
+	copy(result, v.interactions)
+	return result
+}
+
+// Adapter returns the adapter of vcr
+func (v *VCR) Adapter() Adapter {
+	return v.handle
+}
+
+func (v *VCR) redact(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range v.config.RedactHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(key)]; ok {
+			header.Set(key, vcrRedacted)
+		}
+	}
+	return header
+}
+
+// find finds the first unused interaction which matches the request,
+// the last matched one is used if all of them are replayed
+func (v *VCR) find(req *VCRRequest) *VCRInteraction {
+	var matched *VCRInteraction
+	for _, item := range v.interactions {
+		ok := true
+		for _, fn := range v.config.Matchers {
+			if !fn(req, item.Request) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		matched = item
+		if !v.used[item] {
+			break
+		}
+	}
+	if matched != nil {
+		v.used[matched] = true
+	}
+	return matched
+}
+
+// save writes the interactions to cassette file
+func (v *VCR) save() error {
+	buf, err := json.MarshalIndent(&vcrCassette{
+		Interactions: v.interactions,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(v.config.Path, buf, 0644)
+}
+
+func (v *VCR) handle(config *Config) (*Response, error) {
+	r := config.Request
+	req := &VCRRequest{
+		Method: r.Method,
+		URL:    config.GetURL(),
+	}
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		req.Body = body
+	}
+	req.Headers = v.redact(r.Header)
+
+	v.mutex.Lock()
+	var interaction *VCRInteraction
+	if v.config.Mode != VCRModeRecord {
+		interaction = v.find(req)
+	}
+	v.mutex.Unlock()
+
+	if interaction == nil {
+		if v.config.Mode == VCRModeReplay {
+			return nil, ErrVCRInteractionNotFound
+		}
+		var err error
+		interaction, err = v.record(config, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res := interaction.Response
+	resp := &Response{
+		Status:  res.Status,
+		Headers: res.Headers.Clone(),
+		Data:    res.Data,
+	}
+	if resp.Headers == nil {
+		resp.Headers = make(http.Header)
+	}
+	if config.Stream {
+		resp.Body = io.NopCloser(bytes.NewReader(res.Data))
+		resp.Data = nil
+	}
+	return resp, nil
+}
+
+// record sends the request and records the interaction
+func (v *VCR) record(config *Config, req *VCRRequest) (*VCRInteraction, error) {
+	resp, err := v.config.Adapter(config)
+	if err != nil {
+		return nil, err
+	}
+	data := resp.Data
+	// 流式响应需读取全部数据后才能录制
+	if resp.Body != nil {
+		defer resp.Body.Close()
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	interaction := &VCRInteraction{
+		Request: req,
+		Response: &VCRResponse{
+			Status:  resp.Status,
+			Headers: v.redact(resp.Headers),
+			Data:    data,
+		},
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.interactions = append(v.interactions, interaction)
+	v.used[interaction] = true
+	err = v.save()
+	if err != nil {
+		return nil, err
+	}
+	return interaction, nil
+}
+
+// String returns the name of vcr mode
+func (m VCRMode) String() string {
+	switch m {
+	case VCRModeRecord:
+		return "record"
+	case VCRModeReplay:
+		return "replay"
+	default:
+		return "record-missing"
+	}
+}
+
+// ParseVCRMode parses vcr mode from string, it's useful for
+// selecting the mode by environment variable
+func ParseVCRMode(value string) VCRMode {
+	switch strings.ToLower(value) {
+	case "record":
+		return VCRModeRecord
+	case "replay":
+		return VCRModeReplay
+	default:
+		return VCRModeRecordMissing
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 tree xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package axios
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVCRMatcher(t *testing.T) {
+	assert := assert.New(t)
+
+	req := &VCRRequest{
+		Method: "GET",
+		URL:    "https://aslant.site/",
+		Headers: http.Header{
+			"X-Version": []string{"1"},
+		},
+		Body: []byte("a"),
+	}
+	recorded := &VCRRequest{
+		Method: "GET",
+		URL:    "https://aslant.site/",
+		Headers: http.Header{
+			"X-Version": []string{"2"},
+		},
+		Body: []byte("b"),
+	}
+	assert.True(VCRMatchMethod(req, recorded))
+	assert.True(VCRMatchURL(req, recorded))
+	assert.False(VCRMatchBody(req, recorded))
+	assert.False(VCRMatchHeaders("X-Version")(req, recorded))
+	assert.True(VCRMatchHeaders("X-Token")(req, recorded))
+
+	assert.Equal(VCRModeRecord, ParseVCRMode("record"))
+	assert.Equal(VCRModeReplay, ParseVCRMode("REPLAY"))
+	assert.Equal(VCRModeRecordMissing, ParseVCRMode(""))
+	assert.Equal("record-missing", VCRModeRecordMissing.String())
+}
+
+func TestVCR(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&count, 1)
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set(headerContentType, contentTypeJSON)
+		_, _ = w.Write([]byte(`{"count":` + strconv.Itoa(int(n)) + `}`))
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "cassette.json")
+
+	newInstance := func(mode VCRMode) *Instance {
+		vcr, err := NewVCR(VCRConfig{
+			Path: file,
+			Mode: mode,
+			RedactHeaders: []string{
+				"Authorization",
+				"Set-Cookie",
+			},
+		})
+		assert.Nil(err)
+		return NewInstance(&InstanceConfig{
+			BaseURL: server.URL,
+			Adapter: vcr.Adapter(),
+			Headers: http.Header{
+				"Authorization": []string{"Bearer token"},
+			},
+		})
+	}
+
+	// 录制
+	ins := newInstance(VCRModeRecord)
+	resp, err := ins.Get("/users/me")
+	assert.Nil(err)
+	assert.Equal(`{"count":1}`, string(resp.Data))
+	resp, err = ins.Get("/users/me")
+	assert.Nil(err)
+	assert.Equal(`{"count":2}`, string(resp.Data))
+	assert.Equal(vcrRedacted, resp.Headers.Get("Set-Cookie"))
+
+	buf, err := os.ReadFile(file)
+	assert.Nil(err)
+	assert.NotContains(string(buf), "Bearer token")
+	assert.NotContains(string(buf), "session=abc")
+
+	// 回放时按顺序返回，用完后返回最后一个
+	ins = newInstance(VCRModeReplay)
+	for _, expected := range []string{
+		`{"count":1}`,
+		`{"count":2}`,
+		`{"count":2}`,
+	} {
+		resp, err = ins.Get("/users/me")
+		assert.Nil(err)
+		assert.Equal(200, resp.Status)
+		assert.Equal(expected, string(resp.Data))
+	}
+	_, err = ins.Get("/books")
+	assert.True(errors.Is(err, ErrVCRInteractionNotFound))
+
+	// 流式响应
+	resp, err = ins.Request(&Config{
+		URL:    "/users/me",
+		Stream: true,
+	})
+	assert.Nil(err)
+	data, _ := io.ReadAll(resp.Body)
+	assert.Nil(resp.Body.Close())
+	assert.Equal(`{"count":2}`, string(data))
+
+	// 仅录制缺失的请求
+	ins = newInstance(VCRModeRecordMissing)
+	resp, err = ins.Get("/users/me")
+	assert.Nil(err)
+	assert.Equal(`{"count":1}`, string(resp.Data))
+	resp, err = ins.Get("/books")
+	assert.Nil(err)
+	assert.Equal(`{"count":3}`, string(resp.Data))
+	assert.Equal(int32(3), atomic.LoadInt32(&count))
+
+	vcr, err := NewVCR(VCRConfig{
+		Path: file,
+		Mode: VCRModeReplay,
+	})
+	assert.Nil(err)
+	assert.Equal(3, len(vcr.Interactions()))
+
+	_, err = NewVCR(VCRConfig{
+		Path: filepath.Join(t.TempDir(), "none.json"),
+		Mode: VCRModeReplay,
+	})
+	assert.True(os.IsNotExist(err))
+}